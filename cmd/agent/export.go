@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"flextopo/pkg/crd"
+	"flextopo/pkg/graph"
+	"fmt"
+	"io"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// exportUsage describes the export command
+const exportUsage = `Usage: flextopo-agent export [-format dot|graphml|json] [-o file] [snapshot]
+
+Renders a captured topology, e.g. from "kubectl get flextopo <node> -o yaml". The snapshot
+is a FlexTopo object, its status or a JSON export, in YAML or JSON. It is read from stdin if
+no file is given.
+`
+
+// runExport implements the export command
+func runExport(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), exportUsage)
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", "dot", "output format, dot, graphml or json")
+	output := flags.String("o", "", "output file, stdout if empty")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return fmt.Errorf("expected at most one snapshot, got %d", flags.NArg())
+	}
+
+	var write func(*graph.FlexTopoGraph, io.Writer) error
+	switch *format {
+	case "dot":
+		write = (*graph.FlexTopoGraph).WriteDOT
+	case "graphml":
+		write = (*graph.FlexTopoGraph).WriteGraphML
+	case "json":
+		write = (*graph.FlexTopoGraph).WriteJSON
+	default:
+		return fmt.Errorf("unknown format %q, expected dot, graphml or json", *format)
+	}
+
+	input := stdin
+	if flags.NArg() == 1 {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return err
+	}
+	topology, err := readSnapshot(data)
+	if err != nil {
+		return err
+	}
+
+	out := stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	return write(topology, out)
+}
+
+// readSnapshot parses a FlexTopo object, its status or a JSON export in YAML or JSON. Objects
+// written by agents that published the topology in the spec are accepted as well.
+func readSnapshot(data []byte) (*graph.FlexTopoGraph, error) {
+	data, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %v", err)
+	}
+	var envelope struct {
+		Status json.RawMessage `json:"status"`
+		Spec   json.RawMessage `json:"spec"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %v", err)
+	}
+	// The topology is in the first of status, spec and the document itself that has nodes
+	for _, candidate := range [][]byte{envelope.Status, envelope.Spec, data} {
+		var topology struct {
+			Nodes json.RawMessage `json:"nodes"`
+		}
+		if json.Unmarshal(candidate, &topology) == nil && topology.Nodes != nil {
+			data = candidate
+			break
+		}
+	}
+	// JSON exports use the field names of the status
+	var topology crd.FlexTopoTopology
+	if err := json.Unmarshal(data, &topology); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %v", err)
+	}
+	return graph.FromTopology(&topology)
+}
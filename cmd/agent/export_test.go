@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flextopo/pkg/graph"
+	"flextopo/pkg/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+// newExportTestGraph builds one socket with 4 cores in core groups of 2 and one GPU
+func newExportTestGraph() *graph.FlexTopoGraph {
+	g := graph.NewFlexTopoGraph(2)
+	var cpuInfos []utils.CPUInfo
+	for cpu := 0; cpu < 4; cpu++ {
+		cpuInfos = append(cpuInfos, utils.CPUInfo{CPUID: cpu, CoreID: cpu})
+	}
+	g.BuildCPUNodes(cpuInfos)
+	gpu := g.NewGPUNode(0, "GPU-a", "NVIDIA GeForce RTX 4090", 24564)
+	g.AddNode(gpu)
+	g.AttachToNUMA(gpu, 0)
+	g.UpdateCPUUsage(graph.Consumer{Pod: "pod-a", Namespace: "default"}, []int{1})
+	g.UpdateRollups()
+	return g
+}
+
+// toYAML renders v as YAML, like kubectl get -o yaml
+func toYAML(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestReadSnapshot(t *testing.T) {
+	original := newExportTestGraph()
+	topology := original.ToTopology()
+	var export bytes.Buffer
+	if err := original.WriteJSON(&export); err != nil {
+		t.Fatal(err)
+	}
+	object := map[string]interface{}{
+		"apiVersion": "flextopo.baichuan-inc.com/v1alpha1",
+		"kind":       "FlexTopo",
+		"metadata":   map[string]interface{}{"name": "node-1"},
+	}
+	withStatus := map[string]interface{}{"spec": map[string]interface{}{}, "status": topology}
+	legacy := map[string]interface{}{"spec": topology}
+	for key, value := range object {
+		withStatus[key] = value
+		legacy[key] = value
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "object with status", data: toYAML(t, withStatus)},
+		{name: "object with legacy spec", data: toYAML(t, legacy)},
+		{name: "status only", data: toYAML(t, topology)},
+		{name: "JSON export", data: export.Bytes()},
+	}
+	for _, tt := range tests {
+		snapshot, err := readSnapshot(tt.data)
+		if err != nil {
+			t.Errorf("%s: readSnapshot() error = %v", tt.name, err)
+			continue
+		}
+		if diff := graph.Diff(original, snapshot); !diff.Empty() {
+			t.Errorf("%s: snapshot differs from the original: %v", tt.name, diff.Events())
+		}
+	}
+
+	for _, data := range []string{"nodes: [", `{"status": {"nodes": [{"id": "core-0", "type": "CPUCore", "children": 1}]}}`} {
+		if _, err := readSnapshot([]byte(data)); err == nil {
+			t.Errorf("readSnapshot(%q) succeeded, want an error", data)
+		}
+	}
+}
+
+func TestRunExport(t *testing.T) {
+	var status bytes.Buffer
+	if err := newExportTestGraph().WriteJSON(&status); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := filepath.Join(t.TempDir(), "node-1.json")
+	if err := os.WriteFile(snapshot, status.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{name: "dot from stdin", args: nil, want: "digraph"},
+		{name: "graphml from file", args: []string{"-format", "graphml", snapshot}, want: "<graphml"},
+		{name: "json from file", args: []string{"-format", "json", snapshot}, want: `"id": "gpu-0"`},
+		{name: "unknown format", args: []string{"-format", "svg"}, wantErr: `unknown format "svg"`},
+		{name: "two snapshots", args: []string{snapshot, snapshot}, wantErr: "expected at most one snapshot"},
+		{name: "missing file", args: []string{snapshot + ".missing"}, wantErr: "no such file"},
+	}
+	for _, tt := range tests {
+		var stdout bytes.Buffer
+		err := runExport(tt.args, bytes.NewReader(status.Bytes()), &stdout)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: runExport() error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: runExport() error = %v", tt.name, err)
+			continue
+		}
+		if !strings.Contains(stdout.String(), tt.want) {
+			t.Errorf("%s: output does not contain %q:\n%s", tt.name, tt.want, stdout.String())
+		}
+	}
+
+	// -o writes to a file instead of stdout
+	output := filepath.Join(t.TempDir(), "node-1.json")
+	var stdout bytes.Buffer
+	if err := runExport([]string{"-format", "json", "-o", output, snapshot}, nil, &stdout); err != nil {
+		t.Fatalf("runExport() error = %v", err)
+	}
+	var document map[string]interface{}
+	data, _ := os.ReadFile(output)
+	if err := json.Unmarshal(data, &document); err != nil || stdout.Len() != 0 {
+		t.Errorf("-o wrote %q to the file and %q to stdout", data, stdout.String())
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"flextopo/pkg/collector"
+	"flextopo/pkg/graph"
 	"flextopo/pkg/reporter"
 	"flextopo/pkg/utils"
 	"os"
@@ -11,6 +12,25 @@ import (
 func main() {
 	logger := &utils.SimpleLogger{}
 
+	// The export command renders a captured topology and exits
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		if err := runExport(os.Args[2:], os.Stdin, os.Stdout); err != nil {
+			logger.Error("Failed to export topology: " + err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// The cleanup command deletes the FlexTopos of removed nodes once, it runs as a single
+	// CronJob for the whole cluster
+	if len(os.Args) > 1 && os.Args[1] == "cleanup" {
+		if err := runCleanup(logger); err != nil {
+			logger.Error("Failed to clean up orphaned FlexTopos: " + err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
 	nodeName := os.Getenv("NODE_NAME")
 	if nodeName == "" {
 		logger.Error("NODE_NAME environment variable not set")
@@ -23,31 +43,50 @@ func main() {
 		os.Exit(1)
 	}
 
-	reporter, err := reporter.NewReporter(nodeName, logger)
+	// The layout of the reported topology defaults to SPEC_LAYOUT
+	options := graph.TopologyOptions{Nested: utils.GetConfig().SpecLayout == utils.SpecLayoutNested}
+	reporter, err := reporter.NewReporter(nodeName, options, logger)
 	if err != nil {
 		logger.Error("Failed to create reporter: " + err.Error())
 		os.Exit(1)
 	}
 
+	// Logging is the default subscriber of topology changes
+	go logEvents(collector.Subscribe(), logger)
+
 	// collect and report topology data every 60 seconds
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
 
-	for {
-		graph, err := collector.Collect()
-		if err != nil {
-			logger.Error("Failed to collect topology data: " + err.Error())
-			continue
+	// Every iteration, including those cut short by an error, waits for the next tick
+	for ; ; <-ticker.C {
+		collection := collector.Collect()
+		if collection.Graph == nil {
+			logger.Error("Failed to collect topology data: " + collection.Err().Error())
 		}
 
-		err = reporter.Report(graph)
-		if err != nil {
+		// Failed cycles are reported too, the status keeps the last valid topology
+		if err := reporter.Report(collection); err != nil {
 			logger.Error("Failed to report topology data: " + err.Error())
-			continue
 		}
+	}
+}
 
-		logger.Info("Successfully reported topology data")
+// runCleanup deletes the FlexTopos whose node no longer exists. FlexTopos are deleted with
+// their node, except for those created before they had an owner.
+func runCleanup(logger utils.Logger) error {
+	cleaner, err := reporter.NewCleaner(logger)
+	if err != nil {
+		return err
+	}
+	deleted, err := cleaner.CleanupOrphans()
+	logger.Infof("Deleted %d orphaned FlexTopos", deleted)
+	return err
+}
 
-		<-ticker.C
+// logEvents is the default event sink, it logs every change
+func logEvents(events <-chan graph.Event, logger utils.Logger) {
+	for event := range events {
+		logger.Infof("Topology change: %s", event)
 	}
 }
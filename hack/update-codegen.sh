@@ -0,0 +1,69 @@
+#!/usr/bin/env bash
+
+# Regenerates the deepcopy functions of pkg/crd and the typed clientset, listers and
+# informers in pkg/client with k8s.io/code-generator:
+#
+#   go install k8s.io/code-generator/cmd/{deepcopy-gen,client-gen,lister-gen,informer-gen}@v0.31.1
+#   hack/update-codegen.sh
+#
+# The generators expect API types in a <group>/<version> package, so the types in pkg/crd
+# are staged as pkg/apis/flextopo/v1alpha1 and the generated imports are pointed back at
+# pkg/crd.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+ROOT=$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)
+BIN=${CODEGEN_BIN:-$(go env GOPATH)/bin}
+HEADER="${ROOT}/hack/boilerplate.go.txt"
+MODULE=flextopo
+APIS="${MODULE}/pkg/apis/flextopo/v1alpha1"
+
+STAGE=$(mktemp -d)
+trap 'rm -rf "${STAGE}"' EXIT
+
+cp "${ROOT}/go.mod" "${ROOT}/go.sum" "${STAGE}/"
+mkdir -p "${STAGE}/pkg/apis/flextopo/v1alpha1"
+for file in "${ROOT}"/pkg/crd/*.go; do
+  case "${file}" in
+    *_test.go | */zz_generated.*) continue ;;
+  esac
+  sed 's/^package crd$/package v1alpha1/' "${file}" > "${STAGE}/pkg/apis/flextopo/v1alpha1/$(basename "${file}")"
+done
+
+cd "${STAGE}"
+"${BIN}/deepcopy-gen" \
+  --go-header-file "${HEADER}" \
+  --output-file zz_generated.deepcopy.go \
+  "${APIS}"
+"${BIN}/client-gen" \
+  --go-header-file "${HEADER}" \
+  --clientset-name versioned \
+  --input-base "${MODULE}/pkg/apis" \
+  --input flextopo/v1alpha1 \
+  --output-dir pkg/client/clientset \
+  --output-pkg "${MODULE}/pkg/client/clientset"
+"${BIN}/lister-gen" \
+  --go-header-file "${HEADER}" \
+  --output-dir pkg/client/listers \
+  --output-pkg "${MODULE}/pkg/client/listers" \
+  "${APIS}"
+"${BIN}/informer-gen" \
+  --go-header-file "${HEADER}" \
+  --versioned-clientset-package "${MODULE}/pkg/client/clientset/versioned" \
+  --listers-package "${MODULE}/pkg/client/listers" \
+  --output-dir pkg/client/informers \
+  --output-pkg "${MODULE}/pkg/client/informers" \
+  "${APIS}"
+
+# Point the generated code at pkg/crd
+find pkg/client -name '*.go' -exec sed -i \
+  -e "s#^\(\s*[A-Za-z0-9_]\+\) \"${APIS}\"#\1 \"${MODULE}/pkg/crd\"#" \
+  -e "s#^\(\s*\)\"${APIS}\"#\1v1alpha1 \"${MODULE}/pkg/crd\"#" {} +
+sed -i 's/^package v1alpha1$/package crd/' pkg/apis/flextopo/v1alpha1/zz_generated.deepcopy.go
+gofmt -w pkg/client pkg/apis/flextopo/v1alpha1/zz_generated.deepcopy.go
+
+rm -rf "${ROOT}/pkg/client"
+cp -r pkg/client "${ROOT}/pkg/client"
+cp pkg/apis/flextopo/v1alpha1/zz_generated.deepcopy.go "${ROOT}/pkg/crd/"
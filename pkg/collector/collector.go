@@ -1,21 +1,85 @@
 package collector
 
 import (
+	"errors"
 	"flextopo/pkg/graph"
 	"flextopo/pkg/utils"
+	"fmt"
+	"time"
+)
+
+// Names of the collectors, as reported in CollectorError
+const (
+	HardwareCollectorName    = "hardware"
+	ResourceCollectorName    = "resource"
+	UtilizationCollectorName = "utilization"
 )
 
 // Collector interface defines methods for collecting topology information
 type Collector interface {
-	// Collect gathers hardware topology and resource allocation information, returns the constructed FlexTopo graph
-	Collect() (*graph.FlexTopoGraph, error)
+	// Collect gathers hardware topology and resource allocation information, returns the
+	// constructed FlexTopo graph and the failures of the individual collectors
+	Collect() *Collection
+	// Subscribe returns a channel receiving the changes between consecutive successful
+	// collections. The first collection is the baseline and produces no events. Volatile
+	// attributes such as utilization are not reported.
+	Subscribe() <-chan graph.Event
+}
+
+// Collection is the outcome of one collection cycle
+type Collection struct {
+	// Graph is a frozen snapshot, nil if the hardware topology or the resource allocations
+	// could not be collected
+	Graph *graph.FlexTopoGraph
+	// Time is when the cycle started
+	Time time.Time
+	// Errors are the failures of the collectors, at most one per collector
+	Errors []*CollectorError
+}
+
+// CollectorError is the failure of a single collector
+type CollectorError struct {
+	// Collector is the name of the collector, e.g. HardwareCollectorName
+	Collector string
+	Err       error
+}
+
+func (e *CollectorError) Error() string {
+	return fmt.Sprintf("%s collector: %v", e.Collector, e.Err)
+}
+
+func (e *CollectorError) Unwrap() error {
+	return e.Err
+}
+
+// Failed returns the error of the named collector, nil if it succeeded or did not run
+func (c *Collection) Failed(collector string) error {
+	for _, err := range c.Errors {
+		if err.Collector == collector {
+			return err
+		}
+	}
+	return nil
+}
+
+// Err returns the failures of all collectors joined, nil if every collector succeeded
+func (c *Collection) Err() error {
+	errs := make([]error, len(c.Errors))
+	for i, err := range c.Errors {
+		errs[i] = err
+	}
+	return errors.Join(errs...)
 }
 
 // DefaultCollector implements the Collector interface
 type DefaultCollector struct {
-	hardwareCollector *HardwareCollector
-	resourceCollector *ResourceCollector
-	logger            utils.Logger
+	hardwareCollector    *HardwareCollector
+	resourceCollector    *ResourceCollector
+	utilizationCollector *UtilizationCollector
+	events               *eventBroadcaster
+	// previous is the stable snapshot of the last successful collection
+	previous *graph.FlexTopoGraph
+	logger   utils.Logger
 }
 
 // NewCollector creates a new instance of DefaultCollector
@@ -26,25 +90,59 @@ func NewCollector(nodeName string, logger utils.Logger) (Collector, error) {
 		return nil, err
 	}
 	return &DefaultCollector{
-		hardwareCollector: hardwareCollector,
-		resourceCollector: resourceCollector,
-		logger:            logger,
+		hardwareCollector:    hardwareCollector,
+		resourceCollector:    resourceCollector,
+		utilizationCollector: NewUtilizationCollector(logger),
+		events:               &eventBroadcaster{logger: logger},
+		logger:               logger,
 	}, nil
 }
 
 // Collect gathers hardware and resource allocation information
-func (dc *DefaultCollector) Collect() (*graph.FlexTopoGraph, error) {
+func (dc *DefaultCollector) Collect() *Collection {
+	collection := &Collection{Time: time.Now()}
+
 	// Collect hardware topology information
 	graph, err := dc.hardwareCollector.CollectHardwareInfo()
 	if err != nil {
-		return nil, err
+		collection.Errors = append(collection.Errors, &CollectorError{Collector: HardwareCollectorName, Err: err})
+		return collection
 	}
 
-	// Collect resource allocation information
+	// Collect resource allocation information, without it every core would be reported free
 	err = dc.resourceCollector.CollectResourceInfo(graph)
 	if err != nil {
-		return nil, err
+		collection.Errors = append(collection.Errors, &CollectorError{Collector: ResourceCollectorName, Err: err})
+		return collection
+	}
+
+	// Collect live utilization information, a failure only loses the load attributes
+	err = dc.utilizationCollector.CollectUtilizationInfo(graph)
+	if err != nil {
+		dc.logger.Warn("Failed to collect utilization information: " + err.Error())
+		collection.Errors = append(collection.Errors, &CollectorError{Collector: UtilizationCollectorName, Err: err})
 	}
 
-	return graph, nil
+	// Aggregate counts are computed last, once every core and GPU has its final status
+	graph.MarkReservedCPUs(utils.GetConfig().ReservedCPUs.List())
+	graph.UpdateRollups()
+
+	// Readers get a frozen copy, so they never observe a graph being modified
+	collection.Graph = graph.Snapshot()
+	dc.publishChanges(collection.Graph)
+	return collection
+}
+
+// Subscribe returns a channel receiving the topology changes between collections
+func (dc *DefaultCollector) Subscribe() <-chan graph.Event {
+	return dc.events.subscribe()
+}
+
+// publishChanges publishes what changed since the previous successful collection
+func (dc *DefaultCollector) publishChanges(snapshot *graph.FlexTopoGraph) {
+	stable := snapshot.StableSnapshot()
+	if dc.previous != nil {
+		dc.events.publish(graph.Diff(dc.previous, stable).Events())
+	}
+	dc.previous = stable
 }
@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"errors"
+	"flextopo/pkg/graph"
+	"flextopo/pkg/utils"
+	"reflect"
+	"testing"
+)
+
+func TestCollectionErrors(t *testing.T) {
+	errNoCgroup := errors.New("no cgroup")
+	collection := &Collection{Errors: []*CollectorError{{Collector: UtilizationCollectorName, Err: errNoCgroup}}}
+
+	if err := collection.Failed(UtilizationCollectorName); !errors.Is(err, errNoCgroup) {
+		t.Errorf("Failed(%q) = %v, want %v", UtilizationCollectorName, err, errNoCgroup)
+	}
+	if err := collection.Failed(HardwareCollectorName); err != nil {
+		t.Errorf("Failed(%q) = %v, want nil", HardwareCollectorName, err)
+	}
+	if err := collection.Err(); err == nil || err.Error() != "utilization collector: no cgroup" {
+		t.Errorf("Err() = %v", err)
+	}
+	if err := (&Collection{}).Err(); err != nil {
+		t.Errorf("Err() of a successful collection = %v, want nil", err)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	dc := &DefaultCollector{events: &eventBroadcaster{logger: &utils.SimpleLogger{}}, logger: &utils.SimpleLogger{}}
+	first, second := dc.Subscribe(), dc.Subscribe()
+	collect := func(cpuUsage float64, utilization float64) {
+		topology := graph.NewFlexTopoGraph(2)
+		topology.BuildCPUNodes([]utils.CPUInfo{{CPUID: 0, CoreID: 0}, {CPUID: 1, CoreID: 1}})
+		if cpuUsage > 0 {
+			topology.UpdateCPUUsage(graph.Consumer{Pod: "pod-a", CPUUsage: cpuUsage}, []int{1})
+		}
+		topology.UpdateCoreUtilization(map[int]float64{0: utilization})
+		dc.publishChanges(topology.Snapshot())
+	}
+
+	// The first collection is the baseline, usage and utilization alone are not reported
+	collect(0, 10)
+	collect(0.5, 50)
+	collect(0.9, 90)
+
+	// Every subscriber receives every change
+	for i, events := range []<-chan graph.Event{first, second} {
+		var keys []string
+		for len(events) > 0 {
+			event := <-events
+			if event.NodeID != "core-1" || event.Type != graph.EventAttributeChanged {
+				t.Errorf("subscriber %d received unexpected event %s", i, event)
+			}
+			keys = append(keys, event.Key)
+		}
+		if want := []string{"consumers", "status", "usedBy"}; !reflect.DeepEqual(keys, want) {
+			t.Errorf("subscriber %d received changes of %v, want %v", i, keys, want)
+		}
+	}
+
+	// A full subscriber drops events instead of blocking the publisher
+	changes := make([]graph.Event, eventBufferSize+1)
+	dc.events.publish(changes)
+	if len(first) != eventBufferSize {
+		t.Errorf("buffered %d events, want %d", len(first), eventBufferSize)
+	}
+}
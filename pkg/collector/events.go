@@ -0,0 +1,48 @@
+package collector
+
+import (
+	"flextopo/pkg/graph"
+	"flextopo/pkg/utils"
+	"sync"
+)
+
+// eventBufferSize is the number of change events buffered for each subscriber
+const eventBufferSize = 1024
+
+// eventBroadcaster fans topology change events out to every subscriber
+type eventBroadcaster struct {
+	mu          sync.Mutex
+	subscribers []chan graph.Event
+	logger      utils.Logger
+}
+
+// subscribe returns a new channel receiving every event published from now on
+func (b *eventBroadcaster) subscribe() <-chan graph.Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := make(chan graph.Event, eventBufferSize)
+	b.subscribers = append(b.subscribers, events)
+	return events
+}
+
+// publish sends events to every subscriber without blocking. A subscriber whose buffer is
+// full loses the events that do not fit, rather than stalling collection.
+func (b *eventBroadcaster) publish(changes []graph.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, events := range b.subscribers {
+		dropped := 0
+		for _, event := range changes {
+			select {
+			case events <- event:
+			default:
+				dropped++
+			}
+		}
+		if dropped > 0 {
+			b.logger.Warnf("Dropped %d topology change events, event subscriber is too slow", dropped)
+		}
+	}
+}
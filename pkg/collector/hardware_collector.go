@@ -2,7 +2,10 @@ package collector
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"flextopo/pkg/graph"
@@ -33,12 +36,20 @@ func (hc *HardwareCollector) CollectHardwareInfo() (*graph.FlexTopoGraph, error)
 		return nil, err
 	}
 
+	// Collect NUMA memory and distance information
+	hc.collectNUMAMemoryInfo(graph)
+	hc.collectNUMADistanceInfo(graph)
+
 	// Collect GPU information
 	err = hc.collectGPUInfo(graph)
 	if err != nil {
 		return nil, err
 	}
 
+	// Collect NIC information
+	hc.collectNICInfo(graph)
+	connectPCIeSwitchPeers(graph)
+
 	return graph, nil
 }
 
@@ -47,24 +58,60 @@ func (hc *HardwareCollector) collectCPUNUMAInfo(graph *graph.FlexTopoGraph) erro
 	hc.logger.Info("Collecting CPU and NUMA information")
 
 	// Use lscpu command to get CPU and NUMA information
-	out, err := exec.Command("lscpu", "-p=CPU,Core,Socket,Node").Output()
-	if err != nil {
-		return fmt.Errorf("failed to execute lscpu: %v", err)
+	var cpuInfos []utils.CPUInfo
+	out, err := exec.Command("lscpu", "-J", "-e="+utils.LSCPUColumns).Output()
+	if err == nil {
+		cpuInfos, err = utils.ParseLSCPUJSON(out)
+		if err != nil {
+			return fmt.Errorf("failed to parse lscpu output: %w", err)
+		}
+	} else {
+		// lscpu before util-linux 2.30 has no JSON output
+		hc.logger.Warnf("lscpu -J failed, falling back to the parsable output: %v", err)
+		out, err = exec.Command("lscpu", "-p=CPU,Core,Socket,Node").Output()
+		if err != nil {
+			return fmt.Errorf("failed to execute lscpu: %v", err)
+		}
+		cpuInfos = utils.ParseLSCPUOutput(string(out))
 	}
 
 	// Parse output, build nodes and relationships
-	cpuInfo := utils.ParseLSCPUOutput(string(out))
-	graph.BuildCPUNodes(cpuInfo)
+	graph.BuildCPUNodes(hc.placeableCPUs(cpuInfos))
 
 	return nil
 }
 
+// placeableCPUs drops offline CPUs whose core or socket is unknown and assigns CPUs without
+// a NUMA node to NUMA node 0, as kernels without NUMA support treat memory as one node
+func (hc *HardwareCollector) placeableCPUs(cpuInfos []utils.CPUInfo) []utils.CPUInfo {
+	var unknown, noNUMA []int
+	placeable := make([]utils.CPUInfo, 0, len(cpuInfos))
+	for _, cpuInfo := range cpuInfos {
+		if cpuInfo.CoreID < 0 || cpuInfo.SocketID < 0 {
+			unknown = append(unknown, cpuInfo.CPUID)
+			continue
+		}
+		if cpuInfo.NumaNodeID == utils.NoNUMANode {
+			noNUMA = append(noNUMA, cpuInfo.CPUID)
+			cpuInfo.NumaNodeID = 0
+		}
+		placeable = append(placeable, cpuInfo)
+	}
+	if len(unknown) > 0 {
+		hc.logger.Warnf("Skipping offline CPUs %s, their core and socket are unknown", utils.NewCPUSet(unknown...))
+	}
+	if len(noNUMA) > 0 {
+		hc.logger.Warnf("lscpu reports no NUMA node for CPUs %s, assuming NUMA node 0", utils.NewCPUSet(noNUMA...))
+	}
+	return placeable
+}
+
 // collectGPUInfo collects GPU information
 func (hc *HardwareCollector) collectGPUInfo(graph *graph.FlexTopoGraph) error {
 	hc.logger.Info("Collecting GPU information")
 
 	// Use nvidia-smi command to get GPU information
-	out, err := exec.Command("/host-bin/nvidia-smi", "--query-gpu=index,uuid,name,memory.total", "--format=csv,noheader,nounits").Output()
+	out, err := exec.Command("/host-bin/nvidia-smi", "--query-gpu=index,uuid,name,memory.total,pci.bus_id", "--format=csv,noheader,nounits").Output()
 	if err != nil {
 		hc.logger.Warn("nvidia-smi command failed, assuming no GPUs present")
 		return nil // No GPUs, return directly
@@ -85,8 +132,152 @@ func (hc *HardwareCollector) collectGPUInfo(graph *graph.FlexTopoGraph) error {
 		memoryTotal := utils.Atoi(fields[3])
 
 		gpuNode := graph.NewGPUNode(index, uuid, name, memoryTotal)
+		pciBusID := ""
+		if len(fields) >= 5 {
+			// Indexed attributes must be set before the node is added
+			pciBusID = utils.NormalizePCIAddress(fields[4])
+			gpuNode.GPU().PCIBusID = pciBusID
+			if devicePath, err := filepath.EvalSymlinks(filepath.Join("/host-sys/bus/pci/devices", pciBusID)); err == nil {
+				setPCIeSwitch(gpuNode, devicePath)
+			}
+		}
 		graph.AddNode(gpuNode)
+
+		// Attach the GPU to its local NUMA node
+		if pciBusID != "" {
+			hc.attachPCIDevice(graph, gpuNode, pciBusID)
+		}
 	}
 
 	return nil
 }
+
+// collectNUMAMemoryInfo collects the total and free memory of every NUMA node
+func (hc *HardwareCollector) collectNUMAMemoryInfo(graph *graph.FlexTopoGraph) {
+	paths, _ := filepath.Glob("/host-sys/devices/system/node/node*/meminfo")
+	for _, path := range paths {
+		numaNodeID, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(filepath.Dir(path)), "node"))
+		if err != nil {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			hc.logger.Warn("Failed to read " + path + ": " + err.Error())
+			continue
+		}
+		memTotal, memFree, err := utils.ParseNodeMeminfo(string(content))
+		if err != nil {
+			hc.logger.Warn("Failed to parse " + path + ": " + err.Error())
+			continue
+		}
+		graph.SetNUMAMemory(numaNodeID, memTotal/1024, memFree/1024)
+	}
+}
+
+// collectNUMADistanceInfo connects NUMA nodes with their firmware reported distances
+func (hc *HardwareCollector) collectNUMADistanceInfo(graph *graph.FlexTopoGraph) {
+	paths, _ := filepath.Glob("/host-sys/devices/system/node/node*/distance")
+	for _, path := range paths {
+		from, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(filepath.Dir(path)), "node"))
+		if err != nil {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			hc.logger.Warn("Failed to read " + path + ": " + err.Error())
+			continue
+		}
+		// The file lists the distance to every NUMA node in order, e.g. "10 21"
+		for to, field := range strings.Fields(string(content)) {
+			distance, err := strconv.Atoi(field)
+			if err != nil || to == from {
+				continue
+			}
+			graph.SetNUMADistance(from, to, distance)
+		}
+	}
+}
+
+// collectNICInfo collects physical network interfaces, i.e. those backed by a PCI device
+func (hc *HardwareCollector) collectNICInfo(graph *graph.FlexTopoGraph) {
+	hc.logger.Info("Collecting NIC information")
+
+	entries, err := os.ReadDir("/host-sys/class/net")
+	if err != nil {
+		hc.logger.Warn("Failed to list network interfaces: " + err.Error())
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		devicePath, err := filepath.EvalSymlinks(filepath.Join("/host-sys/class/net", name, "device"))
+		if err != nil {
+			// Virtual interfaces have no device
+			continue
+		}
+		pciBusID := filepath.Base(devicePath)
+		nicNode := graph.NewNICNode(name, pciBusID)
+		setPCIeSwitch(nicNode, devicePath)
+		graph.AddNode(nicNode)
+		hc.attachPCIDevice(graph, nicNode, pciBusID)
+	}
+}
+
+// attachPCIDevice attaches a PCI device node to the NUMA node reported by sysfs
+func (hc *HardwareCollector) attachPCIDevice(graph *graph.FlexTopoGraph, node *graph.Node, pciBusID string) {
+	content, err := os.ReadFile(filepath.Join("/host-sys/bus/pci/devices", pciBusID, "numa_node"))
+	if err != nil {
+		hc.logger.Warn("Failed to get NUMA node of " + node.ID + ": " + err.Error())
+		return
+	}
+	numaNodeID, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil || numaNodeID < 0 {
+		// The platform does not report locality for this device
+		return
+	}
+	if !graph.AttachToNUMA(node, numaNodeID) {
+		hc.logger.Warnf("NUMA node %d of %s not found in topology", numaNodeID, node.ID)
+	}
+}
+
+// connectPCIeSwitchPeers connects GPUs and NICs behind the same PCIe switch with
+// "pcie-switch" edges, their traffic does not need to cross the root complex
+func connectPCIeSwitchPeers(g *graph.FlexTopoGraph) {
+	peers := make(map[string][]*graph.Node)
+	for _, nodeType := range []string{"GPU", "NIC"} {
+		for _, node := range g.NodesByType(nodeType) {
+			if pcieSwitch := pcieSwitchOf(node); pcieSwitch != "" {
+				peers[pcieSwitch] = append(peers[pcieSwitch], node)
+			}
+		}
+	}
+	for _, nodes := range peers {
+		for i := range nodes {
+			for _, peer := range nodes[i+1:] {
+				g.Connect(nodes[i], peer, "pcie-switch", 0)
+			}
+		}
+	}
+}
+
+// setPCIeSwitch records the PCIe switch a device sits behind, devices behind the same switch
+// can exchange data without crossing the root complex
+func setPCIeSwitch(node *graph.Node, devicePath string) {
+	pcieSwitch := utils.PCIeSwitch(devicePath)
+	switch attributes := node.TypedAttributes().(type) {
+	case *graph.GPUAttributes:
+		attributes.PCIeSwitch = pcieSwitch
+	case *graph.NICAttributes:
+		attributes.PCIeSwitch = pcieSwitch
+	}
+}
+
+// pcieSwitchOf returns the PCIe switch of a GPU or NIC, or "" if it is not behind one
+func pcieSwitchOf(node *graph.Node) string {
+	switch attributes := node.TypedAttributes().(type) {
+	case *graph.GPUAttributes:
+		return attributes.PCIeSwitch
+	case *graph.NICAttributes:
+		return attributes.PCIeSwitch
+	}
+	return ""
+}
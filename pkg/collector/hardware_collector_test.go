@@ -105,6 +105,23 @@ func TestParseLSCPUOutput(t *testing.T) {
 	}
 }
 
+func TestPlaceableCPUs(t *testing.T) {
+	hc := NewHardwareCollector(&utils.SimpleLogger{})
+	result := hc.placeableCPUs([]utils.CPUInfo{
+		{CPUID: 0, CoreID: 0, SocketID: 0, NumaNodeID: utils.NoNUMANode},
+		{CPUID: 1, CoreID: 1, SocketID: 0, NumaNodeID: 1, Offline: true},
+		{CPUID: 2, CoreID: -1, SocketID: -1, NumaNodeID: utils.NoNUMANode, Offline: true},
+	})
+
+	expected := []utils.CPUInfo{
+		{CPUID: 0, CoreID: 0, SocketID: 0, NumaNodeID: 0},
+		{CPUID: 1, CoreID: 1, SocketID: 0, NumaNodeID: 1, Offline: true},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("placeableCPUs() = %+v, want %+v", result, expected)
+	}
+}
+
 // Helper function: check if a slice contains a specific CPUInfo
 func containsCPUInfo(slice []utils.CPUInfo, item utils.CPUInfo) bool {
 	for _, v := range slice {
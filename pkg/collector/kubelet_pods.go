@@ -0,0 +1,127 @@
+package collector
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"flextopo/pkg/utils"
+)
+
+// kubeletRequestTimeout bounds a single request to the kubelet
+const kubeletRequestTimeout = 10 * time.Second
+
+// kubeletPodSource reads Pods from the /pods endpoint of the local kubelet, which also
+// covers static Pods and keeps working while the apiserver is unreachable
+type kubeletPodSource struct {
+	url       string
+	tokenFile string
+	client    *http.Client
+	logger    utils.Logger
+
+	// lastPods holds the Pods returned by the previous call, keyed by UID
+	lastPods map[types.UID]*corev1.Pod
+}
+
+// newKubeletPodSource creates a kubeletPodSource for the kubelet configured in config
+func newKubeletPodSource(config *utils.Config, logger utils.Logger) (*kubeletPodSource, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.KubeletInsecureSkipVerify}
+	https := strings.HasPrefix(config.KubeletURL, "https://")
+	if https && config.KubeletInsecureSkipVerify {
+		logger.Warnf("Not verifying the certificate of %s, the bearer token is sent to an unauthenticated endpoint", config.KubeletURL)
+	} else if https && config.KubeletCAFile != "" {
+		caData, err := os.ReadFile(config.KubeletCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificates found in %s", config.KubeletCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &kubeletPodSource{
+		url:       strings.TrimSuffix(config.KubeletURL, "/") + "/pods",
+		tokenFile: config.KubeletTokenFile,
+		client: &http.Client{
+			Timeout:   kubeletRequestTimeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+		logger: logger,
+	}, nil
+}
+
+// Start checks that the kubelet endpoint is reachable
+func (ks *kubeletPodSource) Start(stopCh <-chan struct{}) error {
+	_, err := ks.fetchPods()
+	return err
+}
+
+// ListPods fetches the Pods from the kubelet and compares them with the previous call
+func (ks *kubeletPodSource) ListPods() ([]*corev1.Pod, bool, error) {
+	pods, err := ks.fetchPods()
+	if err != nil {
+		return nil, false, err
+	}
+
+	changed := ks.lastPods == nil || len(pods) != len(ks.lastPods)
+	current := make(map[types.UID]*corev1.Pod, len(pods))
+	for _, pod := range pods {
+		current[pod.UID] = pod
+		if changed {
+			continue
+		}
+		lastPod, exists := ks.lastPods[pod.UID]
+		if !exists || podAllocationChanged(lastPod, pod) {
+			changed = true
+		}
+	}
+	ks.lastPods = current
+
+	return pods, changed, nil
+}
+
+// fetchPods performs a single request against the kubelet /pods endpoint
+func (ks *kubeletPodSource) fetchPods() ([]*corev1.Pod, error) {
+	req, err := http.NewRequest(http.MethodGet, ks.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The read-only port is plain http and needs no credentials
+	if strings.HasPrefix(ks.url, "https://") && ks.tokenFile != "" {
+		token, err := os.ReadFile(ks.tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("kubelet returned %s for %s", resp.Status, ks.url)
+	}
+
+	var podList corev1.PodList
+	if err := json.NewDecoder(resp.Body).Decode(&podList); err != nil {
+		return nil, fmt.Errorf("failed to decode kubelet pod list: %v", err)
+	}
+
+	pods := make([]*corev1.Pod, 0, len(podList.Items))
+	for i := range podList.Items {
+		pods = append(pods, &podList.Items[i])
+	}
+	return pods, nil
+}
@@ -0,0 +1,147 @@
+package collector
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"flextopo/pkg/utils"
+)
+
+// fakeKubelet serves a mutable pod list on /pods
+type fakeKubelet struct {
+	mu        sync.Mutex
+	pods      []corev1.Pod
+	authToken string
+}
+
+func (fk *fakeKubelet) setPods(pods ...*corev1.Pod) {
+	fk.mu.Lock()
+	defer fk.mu.Unlock()
+	fk.pods = nil
+	for _, pod := range pods {
+		fk.pods = append(fk.pods, *pod)
+	}
+}
+
+func (fk *fakeKubelet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/pods" {
+		http.NotFound(w, r)
+		return
+	}
+	if fk.authToken != "" && r.Header.Get("Authorization") != "Bearer "+fk.authToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	fk.mu.Lock()
+	defer fk.mu.Unlock()
+	json.NewEncoder(w).Encode(corev1.PodList{Items: fk.pods})
+}
+
+func TestKubeletPodSourceListPods(t *testing.T) {
+	podA := runningPod("pod-a", "containerd://abc")
+	podA.UID = types.UID("uid-a")
+	kubelet := &fakeKubelet{}
+	kubelet.setPods(podA)
+	server := httptest.NewServer(kubelet)
+	defer server.Close()
+
+	source, err := newKubeletPodSource(&utils.Config{KubeletURL: server.URL}, &utils.SimpleLogger{})
+	if err != nil {
+		t.Fatalf("newKubeletPodSource() error = %v", err)
+	}
+	if err := source.Start(nil); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	pods, changed, err := source.ListPods()
+	if err != nil {
+		t.Fatalf("ListPods() error = %v", err)
+	}
+	if len(pods) != 1 || pods[0].Name != "pod-a" || !changed {
+		t.Fatalf("first ListPods() = %d pods, changed %v; want pod-a, changed true", len(pods), changed)
+	}
+
+	if _, changed, _ := source.ListPods(); changed {
+		t.Errorf("ListPods() reported a change for an identical pod list")
+	}
+
+	// A static pod appearing on the node must be reported as a change
+	staticPod := runningPod("static-pod", "containerd://def")
+	staticPod.UID = types.UID("uid-static")
+	kubelet.setPods(podA, staticPod)
+	pods, changed, _ = source.ListPods()
+	if len(pods) != 2 || !changed {
+		t.Errorf("ListPods() = %d pods, changed %v; want 2 pods, changed true", len(pods), changed)
+	}
+
+	// A restarted container must be reported as a change
+	restarted := podA.DeepCopy()
+	restarted.Status.ContainerStatuses[0].ContainerID = "containerd://xyz"
+	kubelet.setPods(restarted, staticPod)
+	if _, changed, _ := source.ListPods(); !changed {
+		t.Errorf("ListPods() did not report a restarted container")
+	}
+}
+
+func TestKubeletPodSourceAuthentication(t *testing.T) {
+	kubelet := &fakeKubelet{authToken: "secret-token"}
+	server := httptest.NewTLSServer(kubelet)
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("secret-token\n"), 0600); err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caData, 0600); err != nil {
+		t.Fatalf("failed to write CA file: %v", err)
+	}
+
+	// Without the serving CA the certificate cannot be verified
+	untrusted, err := newKubeletPodSource(&utils.Config{
+		KubeletURL:       server.URL,
+		KubeletTokenFile: tokenFile,
+	}, &utils.SimpleLogger{})
+	if err != nil {
+		t.Fatalf("newKubeletPodSource() error = %v", err)
+	}
+	if _, _, err := untrusted.ListPods(); err == nil {
+		t.Errorf("ListPods() against an unverified kubelet should fail")
+	}
+
+	source, err := newKubeletPodSource(&utils.Config{
+		KubeletURL:       server.URL,
+		KubeletTokenFile: tokenFile,
+		KubeletCAFile:    caFile,
+	}, &utils.SimpleLogger{})
+	if err != nil {
+		t.Fatalf("newKubeletPodSource() error = %v", err)
+	}
+	if _, _, err := source.ListPods(); err != nil {
+		t.Errorf("ListPods() error = %v", err)
+	}
+
+	source.tokenFile = ""
+	if _, _, err := source.ListPods(); err == nil {
+		t.Errorf("ListPods() without a token should fail")
+	}
+}
+
+func TestNewPodSourceUnsupported(t *testing.T) {
+	_, err := newPodSource(&utils.Config{PodSource: "kublet"}, "node-1", &utils.SimpleLogger{})
+	if err == nil || !strings.Contains(err.Error(), `"kublet"`) {
+		t.Errorf("newPodSource() error = %v, want unsupported pod source", err)
+	}
+}
@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"flextopo/pkg/utils"
+)
+
+// PodSource provides the Pods running on this node
+type PodSource interface {
+	// Start prepares the source and blocks until it is able to serve Pods
+	Start(stopCh <-chan struct{}) error
+	// ListPods returns the Pods on this node and whether any of them started, stopped
+	// or changed container status since the previous call
+	ListPods() ([]*corev1.Pod, bool, error)
+}
+
+// NewPodSource creates the PodSource selected by the configuration
+func NewPodSource(nodeName string, logger utils.Logger) (PodSource, error) {
+	return newPodSource(utils.GetConfig(), nodeName, logger)
+}
+
+// newPodSource creates the PodSource selected by config
+func newPodSource(config *utils.Config, nodeName string, logger utils.Logger) (PodSource, error) {
+	switch config.PodSource {
+	case utils.PodSourceKubelet:
+		logger.Info("Reading pods from the kubelet at " + config.KubeletURL)
+		return newKubeletPodSource(config, logger)
+	case utils.PodSourceAPIServer:
+		logger.Info("Watching pods through the apiserver")
+		restConfig, err := rest.InClusterConfig()
+		if err != nil {
+			return nil, err
+		}
+		clientset, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return nil, err
+		}
+		return newPodWatcher(clientset, nodeName, logger)
+	}
+	return nil, fmt.Errorf("unsupported pod source %q, expected %q or %q", config.PodSource, utils.PodSourceAPIServer, utils.PodSourceKubelet)
+}
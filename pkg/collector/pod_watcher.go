@@ -0,0 +1,138 @@
+package collector
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"flextopo/pkg/utils"
+)
+
+// podWatcher keeps a node-scoped local cache of Pods and records whether any
+// Pod started, stopped or changed container status since the last read
+type podWatcher struct {
+	factory informers.SharedInformerFactory
+	synced  cache.InformerSynced
+	lister  corelisters.PodLister
+	changed atomic.Bool
+	logger  utils.Logger
+}
+
+// newPodWatcher creates a podWatcher that only watches Pods scheduled to nodeName
+func newPodWatcher(clientset kubernetes.Interface, nodeName string, logger utils.Logger) (*podWatcher, error) {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", nodeName).String()
+		}),
+	)
+	podInformer := factory.Core().V1().Pods()
+
+	pw := &podWatcher{
+		factory: factory,
+		synced:  podInformer.Informer().HasSynced,
+		lister:  podInformer.Lister(),
+		logger:  logger,
+	}
+	// The first read must always trigger a refresh
+	pw.changed.Store(true)
+
+	_, err := podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			pw.changed.Store(true)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPod, ok := oldObj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			newPod, ok := newObj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			if podAllocationChanged(oldPod, newPod) {
+				pw.changed.Store(true)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			pw.changed.Store(true)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// Start starts the informer and blocks until the local cache is synced
+func (pw *podWatcher) Start(stopCh <-chan struct{}) error {
+	pw.factory.Start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, pw.synced) {
+		return fmt.Errorf("failed to sync pod cache")
+	}
+	pw.logger.Info("Pod cache synced")
+	return nil
+}
+
+// ListPods returns the cached Pods on this node and whether any of them changed
+// since the previous call
+func (pw *podWatcher) ListPods() ([]*corev1.Pod, bool, error) {
+	changed := pw.changed.Swap(false)
+	pods, err := pw.lister.List(labels.Everything())
+	if err != nil {
+		// Keep the change pending so the next read retries the refresh
+		if changed {
+			pw.changed.Store(true)
+		}
+		return nil, false, err
+	}
+	return pods, changed, nil
+}
+
+// podAllocationChanged reports whether an update of a Pod can affect the resources
+// it holds, i.e. its phase or the identity or state of any of its containers changed
+func podAllocationChanged(oldPod, newPod *corev1.Pod) bool {
+	if oldPod.Status.Phase != newPod.Status.Phase {
+		return true
+	}
+	oldStates := containerStates(oldPod)
+	newStates := containerStates(newPod)
+	if len(oldStates) != len(newStates) {
+		return true
+	}
+	for i := range oldStates {
+		if oldStates[i] != newStates[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// containerStates summarizes every container status of a Pod as "name/containerID/state"
+func containerStates(pod *corev1.Pod) []string {
+	var states []string
+	statusLists := [][]corev1.ContainerStatus{
+		pod.Status.InitContainerStatuses,
+		pod.Status.ContainerStatuses,
+		pod.Status.EphemeralContainerStatuses,
+	}
+	for _, statuses := range statusLists {
+		for _, status := range statuses {
+			state := "waiting"
+			if status.State.Running != nil {
+				state = "running"
+			} else if status.State.Terminated != nil {
+				state = "terminated"
+			}
+			states = append(states, status.Name+"/"+status.ContainerID+"/"+state)
+		}
+	}
+	return states
+}
@@ -0,0 +1,115 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"flextopo/pkg/utils"
+)
+
+func runningPod(name, containerID string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:        "main",
+					ContainerID: containerID,
+					State:       corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+				},
+			},
+		},
+	}
+}
+
+func TestPodAllocationChanged(t *testing.T) {
+	base := runningPod("pod-a", "containerd://abc")
+
+	relabeled := base.DeepCopy()
+	relabeled.Labels = map[string]string{"foo": "bar"}
+
+	restarted := base.DeepCopy()
+	restarted.Status.ContainerStatuses[0].ContainerID = "containerd://def"
+
+	terminated := base.DeepCopy()
+	terminated.Status.ContainerStatuses[0].State = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}
+
+	succeeded := base.DeepCopy()
+	succeeded.Status.Phase = corev1.PodSucceeded
+
+	withEphemeral := base.DeepCopy()
+	withEphemeral.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{{Name: "debugger"}}
+
+	tests := []struct {
+		name   string
+		newPod *corev1.Pod
+		want   bool
+	}{
+		{name: "Metadata Only", newPod: relabeled, want: false},
+		{name: "Container Restarted", newPod: restarted, want: true},
+		{name: "Container Terminated", newPod: terminated, want: true},
+		{name: "Phase Changed", newPod: succeeded, want: true},
+		{name: "Ephemeral Container Added", newPod: withEphemeral, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := podAllocationChanged(base, tt.newPod); got != tt.want {
+				t.Errorf("podAllocationChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodWatcherListPods(t *testing.T) {
+	clientset := fake.NewSimpleClientset(runningPod("pod-a", "containerd://abc"))
+	pw, err := newPodWatcher(clientset, "node-1", &utils.SimpleLogger{})
+	if err != nil {
+		t.Fatalf("newPodWatcher() error = %v", err)
+	}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	if err := pw.Start(stopCh); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	pods, changed, err := pw.ListPods()
+	if err != nil {
+		t.Fatalf("ListPods() error = %v", err)
+	}
+	if len(pods) != 1 || !changed {
+		t.Fatalf("first ListPods() = %d pods, changed %v; want 1 pod, changed true", len(pods), changed)
+	}
+
+	// Nothing happened since the last read
+	if _, changed, _ := pw.ListPods(); changed {
+		t.Errorf("ListPods() reported a change without any pod event")
+	}
+
+	// A new pod on the node must be reported as a change
+	_, err = clientset.CoreV1().Pods("default").Create(context.TODO(), runningPod("pod-b", "containerd://def"), metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("failed to create pod: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		pods, changed, _ = pw.ListPods()
+		if changed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("ListPods() did not report the new pod")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(pods) != 2 {
+		t.Errorf("ListPods() returned %d pods, want 2", len(pods))
+	}
+}
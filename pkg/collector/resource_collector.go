@@ -1,7 +1,6 @@
 package collector
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -9,63 +8,148 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/fields"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 
 	"flextopo/pkg/graph"
 	"flextopo/pkg/utils"
 )
 
+// maxAllocationAge bounds how long cached allocations are reused without any Pod event,
+// so that GPUs opened by a process after its container started are still picked up
+const maxAllocationAge = time.Minute
+
+// containerAllocation records the resources held by a single container
+type containerAllocation struct {
+	containerID string
+	consumer    graph.Consumer
+	cpuCores    utils.CPUSet
+	gpuUUIDs    []string
+	// cgroupPath is the cgroup v2 path of the container's main process
+	cgroupPath string
+}
+
+// cpuUsageSample is a reading of the cumulative CPU time of a container cgroup
+type cpuUsageSample struct {
+	usageUsec uint64
+	at        time.Time
+}
+
+// podContainer is a container status of a Pod together with the kind of the container
+type podContainer struct {
+	status corev1.ContainerStatus
+	kind   string
+}
+
 type ResourceCollector struct {
-	clientset *kubernetes.Clientset
-	nodeName  string
-	logger    utils.Logger
+	nodeName string
+	logger   utils.Logger
+	pods     PodSource
+	stopCh   chan struct{}
+
+	// allocations caches the result of the last allocation refresh
+	allocations []containerAllocation
+	refreshedAt time.Time
+	// cpuUsage holds the last CPU usage sample of each container, keyed by container ID
+	cpuUsage map[string]cpuUsageSample
 }
 
 func NewResourceCollector(nodeName string, logger utils.Logger) (*ResourceCollector, error) {
-	config, err := rest.InClusterConfig()
+	pods, err := NewPodSource(nodeName, logger)
 	if err != nil {
 		return nil, err
 	}
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
+	rc := &ResourceCollector{
+		nodeName: nodeName,
+		logger:   logger,
+		pods:     pods,
+		stopCh:   make(chan struct{}),
+	}
+	if err := rc.pods.Start(rc.stopCh); err != nil {
+		rc.Stop()
 		return nil, err
 	}
-	return &ResourceCollector{
-		clientset: clientset,
-		nodeName:  nodeName,
-		logger:    logger,
-	}, nil
+	return rc, nil
+}
+
+// Stop stops reading Pods
+func (rc *ResourceCollector) Stop() {
+	close(rc.stopCh)
 }
 
 func (rc *ResourceCollector) CollectResourceInfo(graph *graph.FlexTopoGraph) error {
 	rc.logger.Info("Collecting dynamic resource allocation information")
 
 	// Get the list of Pods on the current node
-	pods, err := rc.clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
-		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", rc.nodeName).String(),
-	})
+	pods, changed, err := rc.pods.ListPods()
 	if err != nil {
 		return err
 	}
 
-	// Iterate through Pods and update resource allocation status
-	for _, pod := range pods.Items {
-		rc.processPod(&pod, graph)
+	// Only inspect containers again when a Pod started, stopped or changed container status
+	refresh := changed || time.Since(rc.refreshedAt) > maxAllocationAge
+	if refresh {
+		rc.logger.Info("Refreshing resource allocation of " + strconv.Itoa(len(pods)) + " pods")
+		var allocations []containerAllocation
+		for _, pod := range pods {
+			allocations = append(allocations, rc.processPod(pod)...)
+		}
+		rc.allocations = allocations
+		rc.refreshedAt = time.Now()
 	}
 
+	// Update resource allocation status
+	cpuUsage := make(map[string]cpuUsageSample)
+	for _, allocation := range rc.allocations {
+		consumer := allocation.consumer
+		consumer.CPUUsage = rc.containerCPUUsage(allocation, cpuUsage)
+		consumer = graph.UpdateCPUUsage(consumer, allocation.cpuCores.List())
+		if refresh && consumer.RemoteMemory {
+			rc.logger.Warnf("Memory of container %s/%s is remote to its CPUs: %.0f%% on other NUMA nodes",
+				consumer.Pod, consumer.Container, consumer.RemoteMemoryRatio*100)
+		}
+		graph.UpdateGPUUsage(consumer, allocation.gpuUUIDs)
+	}
+	rc.cpuUsage = cpuUsage
+
 	return nil
 }
 
-// processPod processes a single Pod and updates the resource allocation status on the FlexTopo graph
-func (rc *ResourceCollector) processPod(pod *corev1.Pod, graph *graph.FlexTopoGraph) {
-	// Get the process IDs of all containers in the Pod
-	for _, containerStatus := range pod.Status.ContainerStatuses {
-		containerID := containerStatus.ContainerID
+// containerCPUUsage returns the average number of cores a container used since the previous
+// cycle, computed from usage_usec in its cgroup cpu.stat. The new sample is stored in samples.
+func (rc *ResourceCollector) containerCPUUsage(allocation containerAllocation, samples map[string]cpuUsageSample) float64 {
+	if allocation.cgroupPath == "" {
+		return 0
+	}
+	content, err := os.ReadFile(filepath.Join("/host-sys/fs/cgroup", allocation.cgroupPath, "cpu.stat"))
+	if err != nil {
+		return 0
+	}
+	usageUsec, err := utils.ParseCgroupCPUUsage(string(content))
+	if err != nil {
+		return 0
+	}
+	sample := cpuUsageSample{usageUsec: usageUsec, at: time.Now()}
+	samples[allocation.containerID] = sample
+
+	last, ok := rc.cpuUsage[allocation.containerID]
+	if !ok || sample.usageUsec < last.usageUsec {
+		return 0
+	}
+	elapsed := sample.at.Sub(last.at)
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(sample.usageUsec-last.usageUsec) / float64(elapsed.Microseconds())
+}
+
+// processPod processes a single Pod and returns the resources held by each of its running containers
+func (rc *ResourceCollector) processPod(pod *corev1.Pod) []containerAllocation {
+	var allocations []containerAllocation
+	// Get the process IDs of all running containers in the Pod
+	for _, container := range runningContainers(pod) {
+		containerID := container.status.ContainerID
 		if containerID == "" {
 			continue
 		}
@@ -92,26 +176,92 @@ func (rc *ResourceCollector) processPod(pod *corev1.Pod, graph *graph.FlexTopoGr
 			continue
 		}
 		// debugging:
-		// convert []int into []string
-		cpuCoresStr := make([]string, len(cpuCores))
-		for i, core := range cpuCores {
-			cpuCoresStr[i] = strconv.Itoa(core)
+		// rc.logger.Info("====CPU cores of container " + id + ": " + cpuCores.String())
+
+		allocation := containerAllocation{
+			containerID: containerID,
+			consumer: graph.Consumer{
+				Pod:       pod.Name,
+				Namespace: pod.Namespace,
+				Container: container.status.Name,
+				Kind:      container.kind,
+			},
+			cpuCores: cpuCores,
+		}
+
+		// Get the cgroup used to account the CPU time of the container
+		cgroupPath, err := readCgroupPath(pid)
+		if err != nil {
+			rc.logger.Warn("Failed to get cgroup for container " + id + ": " + err.Error())
+		} else {
+			allocation.cgroupPath = cgroupPath
 		}
-		// rc.logger.Info("====CPU cores of container " + id + ": " + strings.Join(cpuCoresStr, ", "))
 
-		// Update the status of corresponding CPU Core nodes in the topology graph
-		graph.UpdateCPUUsage(pod.Name, cpuCores)
+		// Get where the memory of the container lives
+		memsAllowed, err := rc.getContainerMemsAllowed(pid)
+		if err != nil {
+			rc.logger.Warn("Failed to get allowed memory nodes for container " + id + ": " + err.Error())
+		} else {
+			allocation.consumer.MemsAllowed = memsAllowed.List()
+		}
+		numaMemory, err := rc.getContainerNUMAMemory(allocation.cgroupPath, pid)
+		if err != nil {
+			rc.logger.Warn("Failed to get NUMA memory placement for container " + id + ": " + err.Error())
+		} else {
+			allocation.consumer.NUMAMemory = numaMemory
+		}
 
 		// Get the GPUs actually used by the container
 		gpuUUIDs, err := rc.getContainerGPUs(pid)
 		if err != nil {
 			rc.logger.Warn("Failed to get GPUs for container " + id + ": " + err.Error())
-			continue
+		} else {
+			allocation.gpuUUIDs = gpuUUIDs
 		}
 
-		// Update the status of corresponding GPU nodes in the topology graph
-		graph.UpdateGPUUsage(pod.Name, gpuUUIDs)
+		allocations = append(allocations, allocation)
 	}
+	return allocations
+}
+
+// runningContainers returns the containers of a Pod that can hold resources, i.e. regular,
+// init (including restartable sidecars) and ephemeral containers that are currently running.
+// Pods in the Succeeded or Failed phase hold no resources at all.
+func runningContainers(pod *corev1.Pod) []podContainer {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return nil
+	}
+
+	// Init containers with restartPolicy Always are native sidecars
+	sidecars := make(map[string]bool)
+	for _, container := range pod.Spec.InitContainers {
+		if container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			sidecars[container.Name] = true
+		}
+	}
+
+	var containers []podContainer
+	add := func(status corev1.ContainerStatus, kind string) {
+		// Terminated and waiting containers have no process holding resources
+		if status.State.Running == nil || status.ContainerID == "" {
+			return
+		}
+		containers = append(containers, podContainer{status: status, kind: kind})
+	}
+	for _, status := range pod.Status.InitContainerStatuses {
+		if sidecars[status.Name] {
+			add(status, graph.ContainerKindSidecar)
+		} else {
+			add(status, graph.ContainerKindInit)
+		}
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		add(status, graph.ContainerKindRegular)
+	}
+	for _, status := range pod.Status.EphemeralContainerStatuses {
+		add(status, graph.ContainerKindEphemeral)
+	}
+	return containers
 }
 
 // getContainerPID gets the main process PID of the container
@@ -143,30 +293,94 @@ func (rc *ResourceCollector) getContainerPID(runtime, id string) (string, error)
 }
 
 // getContainerCPUCores gets the list of CPU cores actually used by the container
-func (rc *ResourceCollector) getContainerCPUCores(pid string) ([]int, error) {
+func (rc *ResourceCollector) getContainerCPUCores(pid string) (utils.CPUSet, error) {
 	// Read Cpus_allowed_list from /host-proc/<pid>/status
+	return readProcStatusList(pid, "Cpus_allowed_list")
+}
+
+// getContainerMemsAllowed gets the list of NUMA nodes the container may allocate memory on
+func (rc *ResourceCollector) getContainerMemsAllowed(pid string) (utils.CPUSet, error) {
+	// Read Mems_allowed_list from /host-proc/<pid>/status
+	return readProcStatusList(pid, "Mems_allowed_list")
+}
+
+// getContainerNUMAMemory gets the resident bytes of the container on each NUMA node from
+// memory.numa_stat of its cgroup, which accounts every process of the container
+func (rc *ResourceCollector) getContainerNUMAMemory(cgroupPath, pid string) (map[int]int64, error) {
+	return readNUMAMemory("/host-sys/fs/cgroup", "/host-proc", cgroupPath, pid)
+}
+
+// readNUMAMemory reads memory.numa_stat of the cgroup below cgroupRoot. If the cgroup is
+// unknown or has no memory controller, it sums numa_maps below procRoot over the processes
+// of the cgroup, or of pid alone.
+func readNUMAMemory(cgroupRoot, procRoot, cgroupPath, pid string) (map[int]int64, error) {
+	pids := []string{pid}
+	if cgroupPath != "" {
+		cgroupDir := filepath.Join(cgroupRoot, cgroupPath)
+		if content, err := os.ReadFile(filepath.Join(cgroupDir, "memory.numa_stat")); err == nil {
+			return utils.ParseMemoryNUMAStat(string(content))
+		}
+		if content, err := os.ReadFile(filepath.Join(cgroupDir, "cgroup.procs")); err == nil && len(strings.Fields(string(content))) > 0 {
+			pids = strings.Fields(string(content))
+		}
+	}
+
+	numaMemory := make(map[int]int64)
+	var readErr error
+	read := 0
+	for _, pid := range pids {
+		content, err := os.ReadFile(filepath.Join(procRoot, pid, "numa_maps"))
+		if err != nil {
+			// Processes may exit while they are read
+			readErr = err
+			continue
+		}
+		for numaNodeID, bytes := range utils.ParseNUMAMaps(string(content)) {
+			numaMemory[numaNodeID] += bytes
+		}
+		read++
+	}
+	if read == 0 {
+		return nil, readErr
+	}
+	return numaMemory, nil
+}
+
+// readProcStatusList reads a list field such as Cpus_allowed_list from /host-proc/<pid>/status
+func readProcStatusList(pid, field string) (utils.CPUSet, error) {
 	path := filepath.Join("/host-proc", pid, "status")
 	content, err := os.ReadFile(path)
 	if err != nil {
-		return nil, err
+		return utils.CPUSet{}, err
 	}
 	lines := strings.Split(string(content), "\n")
-	var cpuListLine string
+	var listLine string
 	for _, line := range lines {
-		if strings.HasPrefix(line, "Cpus_allowed_list:") {
-			cpuListLine = strings.TrimSpace(strings.TrimPrefix(line, "Cpus_allowed_list:"))
+		if strings.HasPrefix(line, field+":") {
+			listLine = strings.TrimSpace(strings.TrimPrefix(line, field+":"))
 			break
 		}
 	}
-	if cpuListLine == "" {
-		return nil, fmt.Errorf("failed to find Cpus_allowed_list in %s", path)
+	if listLine == "" {
+		return utils.CPUSet{}, fmt.Errorf("failed to find %s in %s", field, path)
 	}
-	// Parse CPU list
-	cpuCores, err := utils.ParseCPUList(cpuListLine)
+	// Parse CPU or NUMA node list
+	return utils.ParseCPUSet(listLine)
+}
+
+// readCgroupPath returns the cgroup v2 path of a process from /host-proc/<pid>/cgroup
+func readCgroupPath(pid string) (string, error) {
+	path := filepath.Join("/host-proc", pid, "cgroup")
+	content, err := os.ReadFile(path)
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(line, "0::") {
+			return strings.TrimPrefix(line, "0::"), nil
+		}
 	}
-	return cpuCores, nil
+	return "", fmt.Errorf("no cgroup v2 entry in %s", path)
 }
 
 // getContainerGPUs gets the list of GPU UUIDs actually used by the container
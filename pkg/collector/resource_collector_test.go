@@ -0,0 +1,109 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"flextopo/pkg/graph"
+)
+
+func TestRunningContainers(t *testing.T) {
+	always := corev1.ContainerRestartPolicyAlways
+	running := corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	terminated := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}
+
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{Name: "setup"},
+				{Name: "proxy", RestartPolicy: &always},
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			InitContainerStatuses: []corev1.ContainerStatus{
+				{Name: "setup", ContainerID: "containerd://setup", State: terminated},
+				{Name: "proxy", ContainerID: "containerd://proxy", State: running},
+			},
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "main", ContainerID: "containerd://main", State: running},
+				{Name: "crashed", ContainerID: "containerd://crashed", State: terminated},
+				{Name: "pending", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{}}},
+			},
+			EphemeralContainerStatuses: []corev1.ContainerStatus{
+				{Name: "debugger", ContainerID: "containerd://debugger", State: running},
+			},
+		},
+	}
+
+	got := make(map[string]string)
+	for _, container := range runningContainers(pod) {
+		got[container.status.Name] = container.kind
+	}
+	want := map[string]string{
+		"proxy":    graph.ContainerKindSidecar,
+		"main":     graph.ContainerKindRegular,
+		"debugger": graph.ContainerKindEphemeral,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("runningContainers() = %v, want %v", got, want)
+	}
+
+	// Completed Pods hold no resources even if a status is stale
+	for _, phase := range []corev1.PodPhase{corev1.PodSucceeded, corev1.PodFailed} {
+		completed := pod.DeepCopy()
+		completed.Status.Phase = phase
+		if containers := runningContainers(completed); len(containers) != 0 {
+			t.Errorf("runningContainers() in phase %s = %d containers, want 0", phase, len(containers))
+		}
+	}
+}
+
+func TestReadNUMAMemory(t *testing.T) {
+	cgroupRoot, procRoot := t.TempDir(), t.TempDir()
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The main process and a forked worker of the container
+	writeFile(filepath.Join(procRoot, "100", "numa_maps"), "7f3a40000000 default anon=2 N0=2 kernelpagesize_kB=4\n")
+	writeFile(filepath.Join(procRoot, "101", "numa_maps"), "7f3a40000000 default anon=3 N1=3 kernelpagesize_kB=4\n")
+	writeFile(filepath.Join(cgroupRoot, "pod-a", "ctr", "cgroup.procs"), "100\n101\n")
+
+	tests := []struct {
+		name       string
+		cgroupPath string
+		numaStat   string
+		want       map[int]int64
+	}{
+		{name: "sum over cgroup.procs", cgroupPath: "/pod-a/ctr", want: map[int]int64{0: 2 * 4096, 1: 3 * 4096}},
+		{name: "main process without cgroup", want: map[int]int64{0: 2 * 4096}},
+		{name: "memory.numa_stat", cgroupPath: "/pod-a/ctr", numaStat: "anon N0=8192 N1=65536\nfile N0=4096 N1=0\n",
+			want: map[int]int64{0: 12288, 1: 65536}},
+	}
+	for _, tt := range tests {
+		if tt.numaStat != "" {
+			writeFile(filepath.Join(cgroupRoot, tt.cgroupPath, "memory.numa_stat"), tt.numaStat)
+		}
+		got, err := readNUMAMemory(cgroupRoot, procRoot, tt.cgroupPath, "100")
+		if err != nil {
+			t.Fatalf("%s: readNUMAMemory() error = %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: readNUMAMemory() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := readNUMAMemory(cgroupRoot, procRoot, "", "999"); err == nil {
+		t.Errorf("readNUMAMemory() of an exited process should fail")
+	}
+}
@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"flextopo/pkg/graph"
+	"flextopo/pkg/utils"
+)
+
+// UtilizationCollector is responsible for collecting the live load of CPU cores and GPUs
+type UtilizationCollector struct {
+	logger utils.Logger
+	// lastCPUTimes holds the /proc/stat sample of the previous cycle
+	lastCPUTimes map[int]utils.CPUTimes
+}
+
+// NewUtilizationCollector creates a new instance of UtilizationCollector
+func NewUtilizationCollector(logger utils.Logger) *UtilizationCollector {
+	return &UtilizationCollector{
+		logger: logger,
+	}
+}
+
+// CollectUtilizationInfo collects per-core and per-GPU utilization
+func (uc *UtilizationCollector) CollectUtilizationInfo(graph *graph.FlexTopoGraph) error {
+	uc.logger.Info("Collecting utilization information")
+
+	if err := uc.collectCPUUtilization(graph); err != nil {
+		return err
+	}
+	uc.collectGPUUtilization(graph)
+
+	return nil
+}
+
+// collectCPUUtilization computes per-core busy percentage from deltas of /host-proc/stat
+func (uc *UtilizationCollector) collectCPUUtilization(graph *graph.FlexTopoGraph) error {
+	content, err := os.ReadFile("/host-proc/stat")
+	if err != nil {
+		return fmt.Errorf("failed to read /host-proc/stat: %v", err)
+	}
+	cpuTimes, err := utils.ParseProcStat(string(content))
+	if err != nil {
+		return err
+	}
+
+	// The first cycle only records the baseline sample
+	if uc.lastCPUTimes != nil {
+		graph.UpdateCoreUtilization(utils.CPUUtilization(uc.lastCPUTimes, cpuTimes))
+	}
+	uc.lastCPUTimes = cpuTimes
+
+	return nil
+}
+
+// collectGPUUtilization queries SM and memory utilization of every GPU and the memory
+// used by each compute process
+func (uc *UtilizationCollector) collectGPUUtilization(graph *graph.FlexTopoGraph) {
+	out, err := exec.Command("/host-bin/nvidia-smi", "--query-gpu=uuid,utilization.gpu,utilization.memory,memory.used", "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		uc.logger.Warn("nvidia-smi command failed, skipping GPU utilization")
+		return
+	}
+	utilizations := parseGPUUtilization(string(out))
+
+	out, err = exec.Command("/host-bin/nvidia-smi", "--query-compute-apps=pid,gpu_uuid,used_memory", "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		uc.logger.Warn("Failed to query GPU compute processes: " + err.Error())
+	} else {
+		for uuid, processes := range parseGPUProcesses(string(out)) {
+			if utilization, ok := utilizations[uuid]; ok {
+				utilization.Processes = processes
+				utilizations[uuid] = utilization
+			}
+		}
+	}
+
+	for uuid, utilization := range utilizations {
+		graph.UpdateGPUUtilization(uuid, utilization)
+	}
+}
+
+// parseGPUUtilization parses the output of nvidia-smi --query-gpu=uuid,utilization.gpu,utilization.memory,memory.used.
+// Values nvidia-smi reports as "[N/A]" or "[Not Supported]" are left as zero.
+func parseGPUUtilization(output string) map[string]graph.GPUUtilization {
+	utilizations := make(map[string]graph.GPUUtilization)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, ", ")
+		if len(fields) < 4 {
+			continue
+		}
+		var utilization graph.GPUUtilization
+		utilization.SMUtilization, _ = strconv.ParseFloat(fields[1], 64)
+		utilization.MemoryUtilization, _ = strconv.ParseFloat(fields[2], 64)
+		utilization.MemoryUsed, _ = strconv.Atoi(fields[3])
+		utilizations[fields[0]] = utilization
+	}
+	return utilizations
+}
+
+// parseGPUProcesses parses the output of nvidia-smi --query-compute-apps=pid,gpu_uuid,used_memory
+func parseGPUProcesses(output string) map[string][]graph.GPUProcess {
+	processes := make(map[string][]graph.GPUProcess)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, ", ")
+		if len(fields) < 3 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		usedMemory, _ := strconv.Atoi(fields[2])
+		processes[fields[1]] = append(processes[fields[1]], graph.GPUProcess{PID: pid, UsedMemory: usedMemory})
+	}
+	return processes
+}
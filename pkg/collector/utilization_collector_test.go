@@ -0,0 +1,39 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+
+	"flextopo/pkg/graph"
+)
+
+func TestParseGPUUtilization(t *testing.T) {
+	output := `GPU-aaaa, 87, 41, 20480
+GPU-bbbb, 0, 0, 1
+GPU-cccc, [N/A], [N/A], 0
+`
+	got := parseGPUUtilization(output)
+	want := map[string]graph.GPUUtilization{
+		"GPU-aaaa": {SMUtilization: 87, MemoryUtilization: 41, MemoryUsed: 20480},
+		"GPU-bbbb": {MemoryUsed: 1},
+		"GPU-cccc": {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGPUUtilization() = %v, want %v", got, want)
+	}
+}
+
+func TestParseGPUProcesses(t *testing.T) {
+	output := `1234, GPU-aaaa, 20000
+5678, GPU-aaaa, 480
+9012, GPU-bbbb, [N/A]
+`
+	got := parseGPUProcesses(output)
+	want := map[string][]graph.GPUProcess{
+		"GPU-aaaa": {{PID: 1234, UsedMemory: 20000}, {PID: 5678, UsedMemory: 480}},
+		"GPU-bbbb": {{PID: 9012}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGPUProcesses() = %v, want %v", got, want)
+	}
+}
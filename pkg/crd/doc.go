@@ -0,0 +1,5 @@
+// +k8s:deepcopy-gen=package
+// +groupName=flextopo.baichuan-inc.com
+
+// Package crd contains the v1alpha1 API types of the FlexTopo custom resource
+package crd
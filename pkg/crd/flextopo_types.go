@@ -6,6 +6,10 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 // FlexTopo defines the structure of the CRD
 type FlexTopo struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -15,10 +19,39 @@ type FlexTopo struct {
 	Status FlexTopoStatus `json:"status,omitempty"`
 }
 
-// FlexTopoSpec defines the content of the topology graph
-type FlexTopoSpec struct {
-	Nodes []FlexTopoNode `json:"nodes"`
-	Edges []FlexTopoEdge `json:"edges"`
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// FlexTopoList is a list of FlexTopo objects
+type FlexTopoList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []FlexTopo `json:"items"`
+}
+
+// FlexTopoSpec is reserved for desired settings of the node, such as the core group size. The
+// agent only writes the status.
+type FlexTopoSpec struct{}
+
+// FlexTopoTopology defines the content of the topology graph
+type FlexTopoTopology struct {
+	Nodes   []FlexTopoNode   `json:"nodes"`
+	Edges   []FlexTopoEdge   `json:"edges"`
+	Summary *FlexTopoSummary `json:"summary,omitempty"`
+}
+
+// FlexTopoSummary holds the capacity of the whole node
+type FlexTopoSummary struct {
+	CoresTotal    int `json:"coresTotal"`
+	CoresFree     int `json:"coresFree"`
+	CoresUsed     int `json:"coresUsed"`
+	CoresReserved int `json:"coresReserved"`
+	GPUsTotal     int `json:"gpusTotal"`
+	GPUsFree      int `json:"gpusFree"`
+	GPUsUsed      int `json:"gpusUsed"`
+	GPUsReserved  int `json:"gpusReserved"`
+	// MemoryFree is the free memory in MiB, -1 if unknown
+	MemoryFree int `json:"memoryFree"`
 }
 
 // FlexTopoNode represents a node in the topology graph
@@ -34,9 +67,37 @@ type FlexTopoEdge struct {
 	Source string `json:"source"`
 	Target string `json:"target"`
 	Type   string `json:"type"`
+	// Weight scales the weight of the edge type in distance computations, 0 means 1
+	Weight float64 `json:"weight,omitempty"`
 }
 
-// FlexTopoStatus can be used to store status information
+// FlexTopoStatus is the topology and allocations observed by the agent on the node
 type FlexTopoStatus struct {
-	// Add status fields as needed
+	// FlexTopoTopology is the last collected topology that passed validation
+	FlexTopoTopology `json:",inline"`
+
+	// ObservedGeneration is the generation of the spec the agent last observed
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastCollectionTime is when the last collection cycle started, whether it succeeded or not
+	LastCollectionTime *metav1.Time `json:"lastCollectionTime,omitempty"`
+	// AgentVersion is the version of the agent that wrote the status
+	AgentVersion string `json:"agentVersion,omitempty"`
+	// CollectorErrors are the failures of the last collection cycle, one per collector
+	CollectorErrors []string `json:"collectorErrors,omitempty"`
+	// Conditions report the health of the agent and of the published topology
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
+
+// Condition types of FlexTopoStatus
+const (
+	// ConditionHardwareCollected is true if the last cycle collected the hardware topology
+	ConditionHardwareCollected = "HardwareCollected"
+	// ConditionAllocationsCollected is true if the last cycle collected the allocations of
+	// pods, it is unknown if the hardware topology could not be collected
+	ConditionAllocationsCollected = "AllocationsCollected"
+	// ConditionTopologyValid is true if the last collected topology passed validation. While
+	// it is false the status keeps the last valid topology.
+	ConditionTopologyValid = "TopologyValid"
+	// ConditionReported is true if the status holds the topology of the last cycle
+	ConditionReported = "Reported"
+)
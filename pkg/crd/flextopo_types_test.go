@@ -0,0 +1,175 @@
+package crd_test
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/yaml"
+
+	"flextopo/pkg/client/clientset/versioned/fake"
+	"flextopo/pkg/client/informers/externalversions"
+	"flextopo/pkg/crd"
+	"flextopo/pkg/graph"
+)
+
+func newFlexTopo(name string) *crd.FlexTopo {
+	child := &crd.FlexTopoNode{ID: "core-0", Type: "CPUCore", Attributes: runtime.RawExtension{Raw: []byte(`{"status":"free"}`)}}
+	return &crd.FlexTopo{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: crd.FlexTopoStatus{
+			FlexTopoTopology: crd.FlexTopoTopology{
+				Nodes:   []crd.FlexTopoNode{{ID: "coregroup-0-0", Type: "CoreGroup", Children: []*crd.FlexTopoNode{child}}},
+				Edges:   []crd.FlexTopoEdge{{Source: "numa-0", Target: "numa-1", Type: "numa-distance", Weight: 2.1}},
+				Summary: &crd.FlexTopoSummary{CoresTotal: 1, CoresFree: 1},
+			},
+			LastCollectionTime: &metav1.Time{Time: time.Date(2024, 10, 10, 7, 31, 23, 0, time.UTC)},
+			CollectorErrors:    []string{"utilization collector: no cgroup"},
+			Conditions:         []metav1.Condition{{Type: crd.ConditionTopologyValid, Status: metav1.ConditionTrue}},
+		},
+	}
+}
+
+func TestDeepCopy(t *testing.T) {
+	original := newFlexTopo("node-a")
+	copied := original.DeepCopy()
+	if !reflect.DeepEqual(original, copied) {
+		t.Fatalf("DeepCopy() = %+v, want %+v", copied, original)
+	}
+
+	// The copy shares no memory with the original
+	copied.Status.Nodes[0].Children[0].Attributes.Raw[2] = 'X'
+	copied.Status.Summary.CoresFree = 0
+	copied.Status.LastCollectionTime.Time = time.Time{}
+	copied.Status.CollectorErrors[0] = ""
+	copied.Status.Conditions[0].Status = metav1.ConditionFalse
+	if string(original.Status.Nodes[0].Children[0].Attributes.Raw) != `{"status":"free"}` ||
+		original.Status.Summary.CoresFree != 1 || original.Status.LastCollectionTime.IsZero() ||
+		original.Status.CollectorErrors[0] == "" || original.Status.Conditions[0].Status != metav1.ConditionTrue {
+		t.Errorf("modifying the copy changed the original: %+v", original)
+	}
+}
+
+func TestAddToScheme(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := crd.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() error = %v", err)
+	}
+	for _, obj := range []runtime.Object{&crd.FlexTopo{}, &crd.FlexTopoList{}} {
+		kinds, _, err := scheme.ObjectKinds(obj)
+		if err != nil || kinds[0].GroupVersion() != crd.SchemeGroupVersion {
+			t.Errorf("ObjectKinds(%T) = %v, %v", obj, kinds, err)
+		}
+	}
+}
+
+func TestInformer(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newFlexTopo("node-a"))
+	factory := externalversions.NewSharedInformerFactory(clientset, time.Minute)
+	lister := factory.Flextopo().V1alpha1().FlexTopos().Lister()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	factory.Start(ctx.Done())
+	for informer, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			t.Fatalf("informer %v did not sync", informer)
+		}
+	}
+
+	flexTopo, err := lister.Get("node-a")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !reflect.DeepEqual(flexTopo.Status, newFlexTopo("node-a").Status) {
+		t.Errorf("Get() = %+v", flexTopo.Status)
+	}
+
+	if _, err := clientset.FlextopoV1alpha1().FlexTopos().Create(ctx, newFlexTopo("node-b"), metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	err = wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		_, err := lister.Get("node-b")
+		return err == nil, nil
+	})
+	if err != nil {
+		t.Errorf("the lister did not observe node-b")
+	}
+}
+
+// TestManifestAttributes checks that the CRD manifest publishes the type, unit and allowed
+// values of every attribute of the registered node schemas
+func TestManifestAttributes(t *testing.T) {
+	data, err := os.ReadFile("../../deploy/crd.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest map[string]interface{}
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	properties := lookup(t, manifest, "spec", "versions", 0, "schema", "openAPIV3Schema", "properties",
+		"status", "properties", "nodes", "items", "properties", "attributes", "properties").(map[string]interface{})
+
+	types := map[graph.AttributeKind]string{
+		graph.KindInt: "integer", graph.KindFloat: "number", graph.KindString: "string",
+		graph.KindConsumers: "array", graph.KindGPUProcesses: "array",
+	}
+	for _, nodeType := range graph.NodeTypes() {
+		schema, _ := graph.LookupSchema(nodeType)
+		for name, attribute := range schema {
+			property, ok := properties[name].(map[string]interface{})
+			if !ok {
+				t.Errorf("attribute %q of %s is not in the manifest", name, nodeType)
+				continue
+			}
+			if property["type"] != types[attribute.Kind] {
+				t.Errorf("attribute %q has type %v in the manifest, want %s", name, property["type"], types[attribute.Kind])
+			}
+			description, _ := property["description"].(string)
+			if !strings.Contains(description, nodeType) {
+				t.Errorf("description of %q does not name %s: %q", name, nodeType, description)
+			}
+			if attribute.Unit != "" && !strings.Contains(description, "in "+attribute.Unit) {
+				t.Errorf("description of %q does not give the unit %s: %q", name, attribute.Unit, description)
+			}
+			var enum []string
+			if values, ok := property["enum"].([]interface{}); ok {
+				for _, value := range values {
+					enum = append(enum, value.(string))
+				}
+			}
+			if !reflect.DeepEqual(enum, attribute.Enum) {
+				t.Errorf("attribute %q allows %v in the manifest, want %v", name, enum, attribute.Enum)
+			}
+		}
+	}
+}
+
+// lookup follows a path of map keys and list indexes into a decoded document
+func lookup(t *testing.T, document interface{}, path ...interface{}) interface{} {
+	t.Helper()
+	for _, step := range path {
+		switch key := step.(type) {
+		case string:
+			object, _ := document.(map[string]interface{})
+			document = object[key]
+		case int:
+			list, _ := document.([]interface{})
+			if key >= len(list) {
+				t.Fatalf("index %d out of range at %v", key, path)
+			}
+			document = list[key]
+		}
+		if document == nil {
+			t.Fatalf("%v not found in the manifest", path)
+		}
+	}
+	return document
+}
@@ -0,0 +1,203 @@
+package graph
+
+import (
+	"encoding/json"
+	"flextopo/pkg/crd"
+	"flextopo/pkg/utils"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Attributes that carry the cores of CoreGroup and NUMANode nodes in the compact layout
+const (
+	// CoresAttribute maps each status to the core IDs, N of node core-N, of the cores in that
+	// status, in kernel list format, e.g. {"free": "0-7,64-71", "used": "8-15"}. These are
+	// physical core IDs, not logical CPU numbers: SMT siblings share one core ID.
+	CoresAttribute = "cores"
+	// CoreSetsAttribute lists the cores of a CoreGroup that have attributes besides status and
+	// utilization, grouped by identical attributes
+	CoreSetsAttribute = "coreSets"
+	// CoreUtilizationAttribute maps core IDs of a CoreGroup to their utilization
+	CoreUtilizationAttribute = "coreUtilization"
+)
+
+// coreSet is a set of cores of a CoreGroup with identical attributes in the compact layout
+type coreSet struct {
+	// Cores are the core IDs in kernel list format
+	Cores      string          `json:"cores"`
+	Attributes json.RawMessage `json:"attributes"`
+}
+
+// coreIndex returns N for a core node with ID core-N
+func coreIndex(node *Node) (int, bool) {
+	index, err := strconv.Atoi(strings.TrimPrefix(node.ID, "core-"))
+	if err != nil || node.ID != fmt.Sprintf("core-%d", index) {
+		return 0, false
+	}
+	return index, true
+}
+
+// isCompacted reports whether node is folded into the core lists of its CoreGroup in the
+// compact layout. Cores that the core lists cannot describe stay leaf nodes.
+func (g *FlexTopoGraph) isCompacted(node *Node) bool {
+	if node.Type != "CPUCore" || node.Parent == nil || node.Parent.Type != "CoreGroup" || g.Nodes[node.Parent.ID] != node.Parent {
+		return false
+	}
+	if _, ok := coreIndex(node); !ok {
+		return false
+	}
+	return node.Status() != ""
+}
+
+// compactedCores returns the compacted cores below node
+func (g *FlexTopoGraph) compactedCores(node *Node) []*Node {
+	var cores []*Node
+	for _, descendant := range g.descendants(node, "contains") {
+		if g.isCompacted(descendant) {
+			cores = append(cores, descendant)
+		}
+	}
+	return cores
+}
+
+// compactAttributes returns the attributes of node in the compact layout. CoreGroups carry
+// everything needed to rebuild their cores, NUMANodes only the core lists of their cores.
+func (g *FlexTopoGraph) compactAttributes(node *Node) map[string]interface{} {
+	attributes := node.AttributeMap()
+	if node.Type != "CoreGroup" && node.Type != "NUMANode" {
+		return attributes
+	}
+	cores := g.compactedCores(node)
+	if len(cores) == 0 {
+		return attributes
+	}
+
+	byStatus := make(map[string][]int)
+	for _, core := range cores {
+		index, _ := coreIndex(core)
+		status := core.Status()
+		byStatus[status] = append(byStatus[status], index)
+	}
+	coreLists := make(map[string]string, len(byStatus))
+	for status, indexes := range byStatus {
+		coreLists[status] = utils.NewCPUSet(indexes...).String()
+	}
+	attributes[CoresAttribute] = coreLists
+	if node.Type != "CoreGroup" {
+		return attributes
+	}
+
+	// Group the remaining attributes, usually only cores used by a pod have any
+	byAttributes := make(map[string][]int)
+	utilization := make(map[string]float64)
+	for _, core := range cores {
+		index, _ := coreIndex(core)
+		extra := make(map[string]interface{})
+		for name, value := range core.AttributeMap() {
+			switch name {
+			case "status":
+			case "utilization":
+				utilization[strconv.Itoa(index)] = value.(float64)
+			default:
+				extra[name] = value
+			}
+		}
+		if len(extra) > 0 {
+			// Attributes only hold JSON-compatible values, keys are marshaled sorted
+			key, _ := json.Marshal(extra)
+			byAttributes[string(key)] = append(byAttributes[string(key)], index)
+		}
+	}
+	if len(byAttributes) > 0 {
+		coreSets := make([]coreSet, 0, len(byAttributes))
+		for key, indexes := range byAttributes {
+			coreSets = append(coreSets, coreSet{Cores: utils.NewCPUSet(indexes...).String(), Attributes: json.RawMessage(key)})
+		}
+		sort.Slice(coreSets, func(i, j int) bool {
+			return NaturalLess(coreSets[i].Cores, coreSets[j].Cores)
+		})
+		attributes[CoreSetsAttribute] = coreSets
+	}
+	if len(utilization) > 0 {
+		attributes[CoreUtilizationAttribute] = utilization
+	}
+	return attributes
+}
+
+// expandCores recreates the cores of a CoreGroup in the compact layout and returns the
+// "contains" edges to them. The compact attributes are removed from the node.
+func (g *FlexTopoGraph) expandCores(node *Node) ([]crd.FlexTopoEdge, error) {
+	rawCores, compact := node.Attributes[CoresAttribute]
+	delete(node.Attributes, CoresAttribute)
+	if !compact || node.Type != "CoreGroup" {
+		// NUMANodes only repeat the core lists of their core groups
+		return nil, nil
+	}
+
+	var coreLists map[string]string
+	if err := reencode(rawCores, &coreLists); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", CoresAttribute, err)
+	}
+	cores := make(map[int]*Node)
+	var edges []crd.FlexTopoEdge
+	for status, list := range coreLists {
+		cpuset, err := utils.ParseCPUSet(list)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", CoresAttribute, err)
+		}
+		for _, index := range cpuset.List() {
+			id := fmt.Sprintf("core-%d", index)
+			if _, exists := g.Nodes[id]; exists {
+				return nil, fmt.Errorf("duplicate node %s", id)
+			}
+			core := NewNode(id, "CPUCore")
+			core.CPUCore().Status = status
+			core.Children = []*Node{}
+			g.addNode(core)
+			cores[index] = core
+			edges = append(edges, crd.FlexTopoEdge{Source: node.ID, Target: id, Type: "contains"})
+		}
+	}
+
+	var coreSets []coreSet
+	if rawCoreSets, ok := node.Attributes[CoreSetsAttribute]; ok {
+		if err := reencode(rawCoreSets, &coreSets); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", CoreSetsAttribute, err)
+		}
+		delete(node.Attributes, CoreSetsAttribute)
+	}
+	for _, set := range coreSets {
+		cpuset, err := utils.ParseCPUSet(set.Cores)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", CoreSetsAttribute, err)
+		}
+		for _, index := range cpuset.List() {
+			core, ok := cores[index]
+			if !ok {
+				return nil, fmt.Errorf("%s references core %d, which is not in the core lists", CoreSetsAttribute, index)
+			}
+			// Decode for every core, so that cores do not share attribute values
+			if err := decodeAttributes(core, set.Attributes); err != nil {
+				return nil, fmt.Errorf("core-%d: %v", index, err)
+			}
+		}
+	}
+
+	var utilization map[string]float64
+	if rawUtilization, ok := node.Attributes[CoreUtilizationAttribute]; ok {
+		if err := reencode(rawUtilization, &utilization); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", CoreUtilizationAttribute, err)
+		}
+		delete(node.Attributes, CoreUtilizationAttribute)
+	}
+	for key, busy := range utilization {
+		index, err := strconv.Atoi(key)
+		if err != nil || cores[index] == nil {
+			return nil, fmt.Errorf("%s references core %s, which is not in the core lists", CoreUtilizationAttribute, key)
+		}
+		cores[index].CPUCore().Utilization = busy
+	}
+	return edges, nil
+}
@@ -0,0 +1,83 @@
+package graph
+
+// Kinds of containers that can consume resources
+const (
+	ContainerKindRegular   = "container"
+	ContainerKindInit      = "init"
+	ContainerKindSidecar   = "sidecar"
+	ContainerKindEphemeral = "ephemeral"
+)
+
+// Consumer identifies a container that holds a CPU core or a GPU
+type Consumer struct {
+	Pod       string `json:"pod"`
+	Namespace string `json:"namespace,omitempty"`
+	Container string `json:"container,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+
+	// MemsAllowed lists the NUMA nodes the container may allocate memory on
+	MemsAllowed []int `json:"memsAllowed,omitempty"`
+	// NUMAMemory holds the resident bytes of the container per NUMA node
+	NUMAMemory map[int]int64 `json:"numaMemory,omitempty"`
+	// RemoteMemoryRatio is the share of NUMAMemory that lives outside the NUMA nodes of its CPUs
+	RemoteMemoryRatio float64 `json:"remoteMemoryRatio,omitempty"`
+	// RemoteMemory flags containers whose memory is mostly remote to their CPUs
+	RemoteMemory bool `json:"remoteMemory,omitempty"`
+	// CPUUsage is the average number of cores the container used since the previous cycle
+	CPUUsage float64 `json:"cpuUsage,omitempty"`
+}
+
+// remoteMemoryThreshold is the RemoteMemoryRatio above which memory is considered remote
+const remoteMemoryThreshold = 0.5
+
+// classifyMemory computes the remote memory ratio given the NUMA nodes of the consumer's CPUs
+func (c *Consumer) classifyMemory(localNUMA map[int]bool) {
+	if len(localNUMA) == 0 {
+		return
+	}
+	var total, remote int64
+	for numaNodeID, bytes := range c.NUMAMemory {
+		total += bytes
+		if !localNUMA[numaNodeID] {
+			remote += bytes
+		}
+	}
+	if total == 0 {
+		return
+	}
+	c.RemoteMemoryRatio = float64(remote) / float64(total)
+	c.RemoteMemory = c.RemoteMemoryRatio > remoteMemoryThreshold
+}
+
+// addConsumer records consumer on node and marks the node as used
+func addConsumer(node *Node, consumer Consumer) {
+	usage := node.Usage()
+	usage.Status = StatusUsed
+	usage.UsedBy = consumer.Pod
+	usage.Consumers = append(usage.Consumers, consumer)
+}
+
+// Stable returns the consumer without the fields that change on almost every cycle: the CPU
+// usage and where its memory lives. The identity of the container and MemsAllowed remain.
+func (c Consumer) Stable() Consumer {
+	c.CPUUsage = 0
+	c.NUMAMemory = nil
+	c.RemoteMemoryRatio = 0
+	c.RemoteMemory = false
+	return c
+}
+
+// DeepCopy returns a copy of the consumer that shares no slices or maps with it
+func (c Consumer) DeepCopy() Consumer {
+	if c.MemsAllowed != nil {
+		c.MemsAllowed = append([]int(nil), c.MemsAllowed...)
+	}
+	if c.NUMAMemory != nil {
+		numaMemory := make(map[int]int64, len(c.NUMAMemory))
+		for numaNodeID, bytes := range c.NUMAMemory {
+			numaMemory[numaNodeID] = bytes
+		}
+		c.NUMAMemory = numaMemory
+	}
+	return c
+}
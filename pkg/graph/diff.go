@@ -0,0 +1,235 @@
+package graph
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"unsafe"
+)
+
+// Types of change events
+const (
+	EventNodeAdded        = "NodeAdded"
+	EventNodeRemoved      = "NodeRemoved"
+	EventEdgeAdded        = "EdgeAdded"
+	EventEdgeRemoved      = "EdgeRemoved"
+	EventAttributeChanged = "AttributeChanged"
+)
+
+// VolatileAttributes are attributes that change on almost every cycle, such as live
+// utilization. Ignoring them in a diff leaves only allocation and hardware changes.
+var VolatileAttributes = map[string]bool{
+	"utilization":       true,
+	"smUtilization":     true,
+	"memoryUtilization": true,
+	"memoryUsed":        true,
+	"memoryFree":        true,
+	"processes":         true,
+}
+
+// AttributeChange describes an attribute that differs on a node present in both graphs
+type AttributeChange struct {
+	NodeID string
+	Key    string
+	// Old is nil if the attribute was added, New is nil if it was removed
+	Old interface{}
+	New interface{}
+}
+
+// GraphDiff holds the differences between two graphs
+type GraphDiff struct {
+	AddedNodes       []*Node
+	RemovedNodes     []*Node
+	AddedEdges       []*Edge
+	RemovedEdges     []*Edge
+	AttributeChanges []AttributeChange
+}
+
+// DiffOptions controls how graphs are compared
+type DiffOptions struct {
+	// IgnoreAttributes lists attribute keys that are not compared
+	IgnoreAttributes map[string]bool
+}
+
+// Diff compares two graphs, a nil graph is treated as empty
+func Diff(oldGraph, newGraph *FlexTopoGraph) *GraphDiff {
+	return DiffWithOptions(oldGraph, newGraph, DiffOptions{})
+}
+
+// DiffWithOptions compares two graphs with the given options, a nil graph is treated as empty.
+// All lists in the result are in natural ID order.
+func DiffWithOptions(oldGraph, newGraph *FlexTopoGraph, options DiffOptions) *GraphDiff {
+	unlock := readLockBoth(oldGraph, newGraph)
+	defer unlock()
+	oldNodes, oldEdges := contents(oldGraph)
+	newNodes, newEdges := contents(newGraph)
+
+	diff := &GraphDiff{}
+	for id, newNode := range newNodes {
+		oldNode, exists := oldNodes[id]
+		if !exists {
+			diff.AddedNodes = append(diff.AddedNodes, newNode)
+			continue
+		}
+		diff.AttributeChanges = append(diff.AttributeChanges, diffAttributes(id, oldNode, newNode, options)...)
+	}
+	for id, oldNode := range oldNodes {
+		if _, exists := newNodes[id]; !exists {
+			diff.RemovedNodes = append(diff.RemovedNodes, oldNode)
+		}
+	}
+	for key, newEdge := range newEdges {
+		if _, exists := oldEdges[key]; !exists {
+			diff.AddedEdges = append(diff.AddedEdges, newEdge)
+		}
+	}
+	for key, oldEdge := range oldEdges {
+		if _, exists := newEdges[key]; !exists {
+			diff.RemovedEdges = append(diff.RemovedEdges, oldEdge)
+		}
+	}
+
+	SortNodes(diff.AddedNodes)
+	SortNodes(diff.RemovedNodes)
+	sortEdges(diff.AddedEdges)
+	sortEdges(diff.RemovedEdges)
+	sort.Slice(diff.AttributeChanges, func(i, j int) bool {
+		a, b := diff.AttributeChanges[i], diff.AttributeChanges[j]
+		if a.NodeID != b.NodeID {
+			return NaturalLess(a.NodeID, b.NodeID)
+		}
+		return a.Key < b.Key
+	})
+	return diff
+}
+
+// readLockBoth read-locks both graphs in address order, so concurrent diffs of the same
+// graphs in opposite argument order cannot deadlock behind a waiting writer. It returns
+// the function releasing the locks.
+func readLockBoth(a, b *FlexTopoGraph) func() {
+	if a == b {
+		b = nil
+	}
+	if a != nil && b != nil && uintptr(unsafe.Pointer(b)) < uintptr(unsafe.Pointer(a)) {
+		a, b = b, a
+	}
+	for _, g := range []*FlexTopoGraph{a, b} {
+		if g != nil {
+			g.mu.RLock()
+		}
+	}
+	return func() {
+		for _, g := range []*FlexTopoGraph{b, a} {
+			if g != nil {
+				g.mu.RUnlock()
+			}
+		}
+	}
+}
+
+// contents returns the nodes and edges of g, or empty maps for a nil graph
+func contents(g *FlexTopoGraph) (map[string]*Node, map[string]*Edge) {
+	if g == nil {
+		return map[string]*Node{}, map[string]*Edge{}
+	}
+	return g.Nodes, g.Edges
+}
+
+// diffAttributes compares the attributes of a node present in both graphs
+func diffAttributes(id string, oldNode, newNode *Node, options DiffOptions) []AttributeChange {
+	var changes []AttributeChange
+	oldAttributes, newAttributes := oldNode.AttributeMap(), newNode.AttributeMap()
+	for key, newValue := range newAttributes {
+		if options.IgnoreAttributes[key] {
+			continue
+		}
+		oldValue, exists := oldAttributes[key]
+		if !exists || !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, AttributeChange{NodeID: id, Key: key, Old: oldValue, New: newValue})
+		}
+	}
+	for key, oldValue := range oldAttributes {
+		if options.IgnoreAttributes[key] {
+			continue
+		}
+		if _, exists := newAttributes[key]; !exists {
+			changes = append(changes, AttributeChange{NodeID: id, Key: key, Old: oldValue})
+		}
+	}
+	return changes
+}
+
+// sortEdges sorts edges by source, target and type in natural order
+func sortEdges(edges []*Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.Source.ID != b.Source.ID {
+			return NaturalLess(a.Source.ID, b.Source.ID)
+		}
+		if a.Target.ID != b.Target.ID {
+			return NaturalLess(a.Target.ID, b.Target.ID)
+		}
+		return a.Type < b.Type
+	})
+}
+
+// Empty reports whether the two compared graphs were equivalent
+func (d *GraphDiff) Empty() bool {
+	return len(d.AddedNodes) == 0 && len(d.RemovedNodes) == 0 &&
+		len(d.AddedEdges) == 0 && len(d.RemovedEdges) == 0 && len(d.AttributeChanges) == 0
+}
+
+// Event is a single change between two graphs
+type Event struct {
+	Type string
+	// NodeID and NodeType are set for node and attribute events
+	NodeID   string
+	NodeType string
+	// Source, Target and EdgeType are set for edge events
+	Source   string
+	Target   string
+	EdgeType string
+	// Key, Old and New are set for attribute events
+	Key string
+	Old interface{}
+	New interface{}
+}
+
+// String describes the event, e.g. "core-12 status: free -> used"
+func (e Event) String() string {
+	switch e.Type {
+	case EventNodeAdded:
+		return fmt.Sprintf("%s %s added", e.NodeType, e.NodeID)
+	case EventNodeRemoved:
+		return fmt.Sprintf("%s %s removed", e.NodeType, e.NodeID)
+	case EventEdgeAdded:
+		return fmt.Sprintf("edge %s -%s-> %s added", e.Source, e.EdgeType, e.Target)
+	case EventEdgeRemoved:
+		return fmt.Sprintf("edge %s -%s-> %s removed", e.Source, e.EdgeType, e.Target)
+	case EventAttributeChanged:
+		return fmt.Sprintf("%s %s: %v -> %v", e.NodeID, e.Key, e.Old, e.New)
+	}
+	return e.Type
+}
+
+// Events flattens the diff into events: removals first, then additions, then attribute changes
+func (d *GraphDiff) Events() []Event {
+	var events []Event
+	for _, edge := range d.RemovedEdges {
+		events = append(events, Event{Type: EventEdgeRemoved, Source: edge.Source.ID, Target: edge.Target.ID, EdgeType: edge.Type})
+	}
+	for _, node := range d.RemovedNodes {
+		events = append(events, Event{Type: EventNodeRemoved, NodeID: node.ID, NodeType: node.Type})
+	}
+	for _, node := range d.AddedNodes {
+		events = append(events, Event{Type: EventNodeAdded, NodeID: node.ID, NodeType: node.Type})
+	}
+	for _, edge := range d.AddedEdges {
+		events = append(events, Event{Type: EventEdgeAdded, Source: edge.Source.ID, Target: edge.Target.ID, EdgeType: edge.Type})
+	}
+	for _, change := range d.AttributeChanges {
+		event := Event{Type: EventAttributeChanged, NodeID: change.NodeID, Key: change.Key, Old: change.Old, New: change.New}
+		events = append(events, event)
+	}
+	return events
+}
@@ -0,0 +1,46 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before := newQueryTestGraph()
+
+	after := newQueryTestGraph()
+	delete(after.Nodes, "gpu-1")
+	for key, edge := range after.Edges {
+		if edge.Source.ID == "gpu-1" {
+			delete(after.Edges, key)
+		}
+	}
+	after.Reindex()
+	after.UpdateCPUUsage(Consumer{Pod: "pod-x"}, []int{2})
+	after.UpdateCoreUtilization(map[int]float64{3: 42})
+
+	diff := Diff(before, after)
+	assert.Empty(t, diff.AddedNodes)
+	assert.Equal(t, []string{"gpu-1"}, ids(diff.RemovedNodes))
+	assert.Empty(t, diff.AddedEdges)
+	assert.Equal(t, 1, len(diff.RemovedEdges))
+	assert.Contains(t, diff.AttributeChanges, AttributeChange{NodeID: "core-2", Key: "status", Old: "free", New: "used"})
+	assert.Contains(t, diff.AttributeChanges, AttributeChange{NodeID: "core-2", Key: "usedBy", New: "pod-x"})
+	assert.Contains(t, diff.AttributeChanges, AttributeChange{NodeID: "core-3", Key: "utilization", New: 42.0})
+
+	// Volatile attributes can be ignored
+	diff = DiffWithOptions(before, after, DiffOptions{IgnoreAttributes: VolatileAttributes})
+	for _, change := range diff.AttributeChanges {
+		assert.False(t, VolatileAttributes[change.Key], "unexpected change of %s", change.Key)
+	}
+
+	events := diff.Events()
+	assert.Equal(t, EventEdgeRemoved, events[0].Type)
+	assert.Equal(t, "GPU gpu-1 removed", events[1].String())
+	assert.Contains(t, events, Event{Type: EventAttributeChanged, NodeID: "core-2", Key: "status", Old: "free", New: "used"})
+
+	// Identical graphs and a nil baseline
+	assert.True(t, Diff(after, after.Snapshot()).Empty())
+	assert.Equal(t, len(after.Nodes), len(Diff(nil, after).AddedNodes))
+}
@@ -0,0 +1,176 @@
+package graph
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultEdgeWeights are the weights of the edge types in distance computations.
+// Types that are not listed weigh 1.
+var DefaultEdgeWeights = map[string]float64{
+	"contains":    1,
+	"attached-to": 1,
+	// numa-distance edges carry the firmware distance divided by 10, e.g. 2.1 across sockets
+	"numa-distance": 1,
+	"pcie-switch":   0.5,
+	"nvlink":        0.25,
+}
+
+// Path is the cheapest route between two nodes
+type Path struct {
+	// Nodes are the nodes on the path, from source to destination
+	Nodes []*Node
+	// Edges are the traversed edges, Edges[i] connects Nodes[i] and Nodes[i+1]
+	Edges []*Edge
+	Cost  float64
+}
+
+// String describes the path, e.g. "core-3 -contains- coregroup-0-0 -contains- numa-0 (cost 2.0)"
+func (p Path) String() string {
+	if len(p.Nodes) == 0 {
+		return "no path"
+	}
+	var builder strings.Builder
+	builder.WriteString(p.Nodes[0].ID)
+	for i, edge := range p.Edges {
+		fmt.Fprintf(&builder, " -%s- %s", edge.Type, p.Nodes[i+1].ID)
+	}
+	fmt.Fprintf(&builder, " (cost %.1f)", p.Cost)
+	return builder.String()
+}
+
+// Distance returns the cheapest path between two nodes using DefaultEdgeWeights
+func (g *FlexTopoGraph) Distance(a, b *Node) (Path, error) {
+	return g.DistanceWithWeights(a, b, DefaultEdgeWeights)
+}
+
+// DistanceWithWeights returns the cheapest path between two nodes. Edges are traversed in
+// both directions and cost the weight of their type, scaled by the edge weight if set.
+// Types that are not in weights weigh 1, a negative weight excludes the type.
+func (g *FlexTopoGraph) DistanceWithWeights(a, b *Node, weights map[string]float64) (Path, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.Nodes[a.ID] != a || g.Nodes[b.ID] != b {
+		return Path{}, fmt.Errorf("node %s or %s is not part of the graph", a.ID, b.ID)
+	}
+
+	// Dijkstra over the adjacency index
+	cost := map[*Node]float64{a: 0}
+	via := make(map[*Node]*Edge)
+	done := make(map[*Node]bool)
+	queue := &distanceQueue{{node: a}}
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(distanceItem)
+		if done[item.node] {
+			continue
+		}
+		done[item.node] = true
+		if item.node == b {
+			break
+		}
+		for _, edge := range g.incidentEdges(item.node) {
+			weight := edgeWeight(edge, weights)
+			if weight < 0 {
+				continue
+			}
+			next := edge.Target
+			if next == item.node {
+				next = edge.Source
+			}
+			nextCost := item.cost + weight
+			if known, seen := cost[next]; !seen || nextCost < known {
+				cost[next] = nextCost
+				via[next] = edge
+				heap.Push(queue, distanceItem{node: next, cost: nextCost})
+			}
+		}
+	}
+	if !done[b] {
+		return Path{}, fmt.Errorf("no path between %s and %s", a.ID, b.ID)
+	}
+
+	// Walk back from the destination
+	path := Path{Nodes: []*Node{b}, Cost: cost[b]}
+	for node := b; node != a; {
+		edge := via[node]
+		if edge.Source == node {
+			node = edge.Target
+		} else {
+			node = edge.Source
+		}
+		path.Nodes = append(path.Nodes, node)
+		path.Edges = append(path.Edges, edge)
+	}
+	for i, j := 0, len(path.Nodes)-1; i < j; i, j = i+1, j-1 {
+		path.Nodes[i], path.Nodes[j] = path.Nodes[j], path.Nodes[i]
+	}
+	for i, j := 0, len(path.Edges)-1; i < j; i, j = i+1, j-1 {
+		path.Edges[i], path.Edges[j] = path.Edges[j], path.Edges[i]
+	}
+	return path, nil
+}
+
+// incidentEdges returns all edges entering or leaving node, in a stable order
+func (g *FlexTopoGraph) incidentEdges(node *Node) []*Edge {
+	var edges []*Edge
+	for _, adjacency := range []map[string][]*Edge{g.index.out[node.ID], g.index.in[node.ID]} {
+		for _, edgeType := range sortedKeys(adjacency) {
+			edges = append(edges, adjacency[edgeType]...)
+		}
+	}
+	return edges
+}
+
+// edgeWeight is the cost of traversing edge
+func edgeWeight(edge *Edge, weights map[string]float64) float64 {
+	weight, ok := weights[edge.Type]
+	if !ok {
+		weight = 1
+	}
+	if edge.Weight > 0 && weight > 0 {
+		weight *= edge.Weight
+	}
+	return weight
+}
+
+// sortedKeys returns the keys of an adjacency map in order
+func sortedKeys(adjacency map[string][]*Edge) []string {
+	keys := make([]string, 0, len(adjacency))
+	for key := range adjacency {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// distanceItem is a node queued with its tentative cost
+type distanceItem struct {
+	node *Node
+	cost float64
+}
+
+// distanceQueue is a min-heap of distanceItems ordered by cost, then node ID
+type distanceQueue []distanceItem
+
+func (q distanceQueue) Len() int { return len(q) }
+
+func (q distanceQueue) Less(i, j int) bool {
+	if q[i].cost != q[j].cost {
+		return q[i].cost < q[j].cost
+	}
+	return NaturalLess(q[i].node.ID, q[j].node.ID)
+}
+
+func (q distanceQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *distanceQueue) Push(x interface{}) { *q = append(*q, x.(distanceItem)) }
+
+func (q *distanceQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
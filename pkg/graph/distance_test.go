@@ -0,0 +1,44 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistance(t *testing.T) {
+	graph := newQueryTestGraph()
+	core, gpu0, gpu1 := graph.Nodes["core-0"], graph.Nodes["gpu-0"], graph.Nodes["gpu-1"]
+
+	path, err := graph.Distance(core, gpu0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"core-0", "coregroup-0-0", "numa-0", "gpu-0"}, ids(path.Nodes))
+	assert.Equal(t, 3.0, path.Cost)
+	assert.Equal(t, "core-0 -contains- coregroup-0-0 -contains- numa-0 -attached-to- gpu-0 (cost 3.0)", path.String())
+
+	// The sockets are not connected until the NUMA distances are known
+	_, err = graph.Distance(gpu0, gpu1)
+	assert.Error(t, err)
+	assert.True(t, graph.SetNUMADistance(0, 1, 21))
+	assert.False(t, graph.SetNUMADistance(0, 7, 21))
+	path, err = graph.Distance(gpu0, gpu1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"gpu-0", "numa-0", "numa-1", "gpu-1"}, ids(path.Nodes))
+	assert.InDelta(t, 4.1, path.Cost, 1e-9)
+
+	// A PCIe switch shortcut is preferred unless its type is excluded
+	graph.Connect(gpu0, gpu1, "pcie-switch", 0)
+	path, err = graph.Distance(gpu1, gpu0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"gpu-1", "gpu-0"}, ids(path.Nodes))
+	assert.Equal(t, 0.5, path.Cost)
+	path, err = graph.DistanceWithWeights(gpu1, gpu0, map[string]float64{"pcie-switch": -1})
+	assert.NoError(t, err)
+	assert.InDelta(t, 4.1, path.Cost, 1e-9)
+
+	path, err = graph.Distance(core, core)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, path.Cost)
+	_, err = graph.Distance(core, &Node{ID: "gpu-9"})
+	assert.Error(t, err)
+}
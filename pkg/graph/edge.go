@@ -5,4 +5,6 @@ type Edge struct {
 	Source *Node
 	Target *Node
 	Type   string
+	// Weight scales the weight of the edge type in distance computations, 0 means 1
+	Weight float64
 }
@@ -0,0 +1,373 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StatusColors are the fill colors of nodes in DOT output by status
+var StatusColors = map[string]string{
+	StatusFree:     "palegreen",
+	StatusUsed:     "salmon",
+	StatusReserved: "khaki",
+	StatusOffline:  "lightgrey",
+}
+
+// dotEdgeStyles are the attributes of edges in DOT output by edge type
+var dotEdgeStyles = map[string]string{
+	"contains":      "",
+	"attached-to":   "style=dashed",
+	"numa-distance": "dir=none, color=grey",
+	"pcie-switch":   "dir=none, style=dotted",
+}
+
+// WriteDOT writes the graph in Graphviz DOT format. Sockets and NUMA nodes are drawn as
+// clusters around the nodes they contain or that are attached to them, and nodes with a
+// status are filled with its color from StatusColors.
+func (g *FlexTopoGraph) WriteDOT(w io.Writer) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "digraph flextopo {")
+	fmt.Fprintln(out, "  node [shape=box, style=filled, fillcolor=white];")
+
+	// Assign every node to the innermost cluster it belongs to
+	nodes := mapValues(g.Nodes)
+	SortNodes(nodes)
+	members := make(map[*Node][]*Node)
+	var sockets, numaNodes, roots []*Node
+	for _, node := range nodes {
+		switch {
+		case node.Type == "Socket":
+			sockets = append(sockets, node)
+			members[node] = append(members[node], node)
+		case node.Type == "NUMANode":
+			numaNodes = append(numaNodes, node)
+			members[node] = append(members[node], node)
+		case g.numaNodeOf(node) != nil:
+			numa := g.numaNodeOf(node)
+			members[numa] = append(members[numa], node)
+		default:
+			roots = append(roots, node)
+		}
+	}
+
+	writeNUMA := func(numa *Node, indent string) {
+		fmt.Fprintf(out, "%ssubgraph %s {\n", indent, dotID("cluster_"+numa.ID))
+		fmt.Fprintf(out, "%s  label=%s;\n", indent, dotID(numa.ID))
+		for _, node := range members[numa] {
+			writeDOTNode(out, indent+"  ", node)
+		}
+		fmt.Fprintf(out, "%s}\n", indent)
+	}
+	for _, socket := range sockets {
+		fmt.Fprintf(out, "  subgraph %s {\n", dotID("cluster_"+socket.ID))
+		fmt.Fprintf(out, "    label=%s;\n", dotID(socket.ID))
+		writeDOTNode(out, "    ", socket)
+		for _, numa := range numaNodes {
+			if numa.Parent == socket {
+				writeNUMA(numa, "    ")
+			}
+		}
+		fmt.Fprintln(out, "  }")
+	}
+	for _, numa := range numaNodes {
+		if numa.Parent == nil || numa.Parent.Type != "Socket" {
+			writeNUMA(numa, "  ")
+		}
+	}
+	for _, node := range roots {
+		writeDOTNode(out, "  ", node)
+	}
+
+	for _, key := range sortedEdgeKeys(g.Edges) {
+		edge := g.Edges[key]
+		// Clusters already show what sockets and NUMA nodes contain
+		if edge.Type == "contains" && (edge.Source.Type == "Socket" || edge.Source.Type == "NUMANode") {
+			continue
+		}
+		style, known := dotEdgeStyles[edge.Type]
+		if !known {
+			style = "label=" + dotID(edge.Type)
+		}
+		if edge.Weight != 0 {
+			if style != "" {
+				style += ", "
+			}
+			style += "label=" + dotID(strconv.FormatFloat(edge.Weight, 'g', -1, 64))
+		}
+		if style != "" {
+			style = " [" + style + "]"
+		}
+		fmt.Fprintf(out, "  %s -> %s%s;\n", dotID(edge.Source.ID), dotID(edge.Target.ID), style)
+	}
+
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
+
+// writeDOTNode writes a node labeled with its ID, name and status
+func writeDOTNode(out io.Writer, indent string, node *Node) {
+	label := []string{node.ID}
+	if name, _ := node.Attribute("name"); name != nil && name != "" {
+		label = append(label, fmt.Sprint(name))
+	}
+	status := node.Status()
+	if status != "" {
+		label = append(label, status)
+	}
+	for i := range label {
+		label[i] = dotEscape(label[i])
+	}
+	fill := ""
+	if color, ok := StatusColors[status]; ok {
+		fill = ", fillcolor=" + color
+	}
+	fmt.Fprintf(out, "%s%s [label=\"%s\"%s];\n", indent, dotID(node.ID), strings.Join(label, `\n`), fill)
+}
+
+// dotID quotes s as a DOT ID
+func dotID(s string) string {
+	return `"` + dotEscape(s) + `"`
+}
+
+// dotEscape escapes s for use in a quoted DOT string
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// GraphML document structure, see http://graphml.graphdrawing.org
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the graph in GraphML format. Every node attribute becomes a GraphML
+// key, typed long, double or boolean if all its values are, values of other attributes
+// are strings with structured values in JSON.
+func (g *FlexTopoGraph) WriteGraphML(w io.Writer) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	nodes := mapValues(g.Nodes)
+	SortNodes(nodes)
+
+	// Collect the values of every attribute to choose its GraphML type
+	values := make(map[string][]interface{})
+	attributes := make(map[*Node]map[string]interface{}, len(nodes))
+	for _, node := range nodes {
+		attributes[node] = node.AttributeMap()
+		for name, value := range attributes[node] {
+			values[name] = append(values[name], value)
+		}
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	document := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "node.type", For: "node", Name: "type", Type: "string"},
+			{ID: "edge.type", For: "edge", Name: "type", Type: "string"},
+			{ID: "edge.weight", For: "edge", Name: "weight", Type: "double"},
+		},
+		Graph: graphMLGraph{ID: "flextopo", EdgeDefault: "directed"},
+	}
+	for _, name := range names {
+		document.Keys = append(document.Keys, graphMLKey{ID: "node." + name, For: "node", Name: name, Type: graphMLType(values[name])})
+	}
+
+	for _, node := range nodes {
+		element := graphMLNode{ID: node.ID, Data: []graphMLData{{Key: "node.type", Value: node.Type}}}
+		for _, name := range names {
+			attribute, present := attributes[node][name]
+			if !present {
+				continue
+			}
+			value, err := graphMLValue(attribute)
+			if err != nil {
+				return fmt.Errorf("node %s: attribute %q: %v", node.ID, name, err)
+			}
+			element.Data = append(element.Data, graphMLData{Key: "node." + name, Value: value})
+		}
+		document.Graph.Nodes = append(document.Graph.Nodes, element)
+	}
+	for _, key := range sortedEdgeKeys(g.Edges) {
+		edge := g.Edges[key]
+		element := graphMLEdge{
+			ID:     key,
+			Source: edge.Source.ID,
+			Target: edge.Target.ID,
+			Data:   []graphMLData{{Key: "edge.type", Value: edge.Type}},
+		}
+		if edge.Weight != 0 {
+			element.Data = append(element.Data, graphMLData{Key: "edge.weight", Value: strconv.FormatFloat(edge.Weight, 'g', -1, 64)})
+		}
+		document.Graph.Edges = append(document.Graph.Edges, element)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// graphMLType returns the GraphML type that fits all values
+func graphMLType(values []interface{}) string {
+	integers, numbers, booleans := true, true, true
+	for _, value := range values {
+		switch value.(type) {
+		case int, int64:
+			booleans = false
+		case float64:
+			integers, booleans = false, false
+		case bool:
+			integers, numbers = false, false
+		default:
+			return "string"
+		}
+	}
+	switch {
+	case integers && numbers:
+		return "long"
+	case numbers:
+		return "double"
+	case booleans:
+		return "boolean"
+	}
+	return "string"
+}
+
+// graphMLValue formats an attribute value for GraphML
+func graphMLValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+// Document is the graph as a plain JSON document, without the FlexTopo envelope. Its nodes
+// and edges use the field names of the status, so FromTopology can read it back.
+type Document struct {
+	Nodes   []DocumentNode `json:"nodes"`
+	Edges   []DocumentEdge `json:"edges"`
+	Summary Capacity       `json:"summary"`
+}
+
+// DocumentNode is a node of a Document
+type DocumentNode struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// Parent is the node containing this node through a "contains" edge
+	Parent     string                 `json:"parent,omitempty"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// DocumentEdge is an edge of a Document
+type DocumentEdge struct {
+	Source string  `json:"source"`
+	Target string  `json:"target"`
+	Type   string  `json:"type"`
+	Weight float64 `json:"weight,omitempty"`
+}
+
+// Document returns the graph as a plain document with nodes and edges in natural ID order
+func (g *FlexTopoGraph) Document() *Document {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	document := &Document{
+		Nodes:   []DocumentNode{},
+		Edges:   []DocumentEdge{},
+		Summary: g.summary(),
+	}
+	nodes := mapValues(g.Nodes)
+	SortNodes(nodes)
+	for _, node := range nodes {
+		documentNode := DocumentNode{
+			ID:         node.ID,
+			Type:       node.Type,
+			Attributes: deepCopyAttributes(node.AttributeMap()),
+		}
+		if node.Parent != nil {
+			documentNode.Parent = node.Parent.ID
+		}
+		document.Nodes = append(document.Nodes, documentNode)
+	}
+	for _, key := range sortedEdgeKeys(g.Edges) {
+		edge := g.Edges[key]
+		document.Edges = append(document.Edges, DocumentEdge{
+			Source: edge.Source.ID,
+			Target: edge.Target.ID,
+			Type:   edge.Type,
+			Weight: edge.Weight,
+		})
+	}
+	return document
+}
+
+// WriteJSON writes the graph as an indented JSON Document
+func (g *FlexTopoGraph) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(g.Document())
+}
@@ -0,0 +1,87 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flextopo/pkg/crd"
+)
+
+func TestWriteDOT(t *testing.T) {
+	graph := newRoundTripGraph()
+	var out bytes.Buffer
+	assert.NoError(t, graph.WriteDOT(&out))
+	dot := out.String()
+
+	assert.True(t, strings.HasPrefix(dot, "digraph flextopo {\n"))
+	assert.Contains(t, dot, `subgraph "cluster_socket-0" {`)
+	assert.Contains(t, dot, `    subgraph "cluster_numa-0" {`)
+	// Cores are filled by status, devices are drawn in the cluster of their NUMA node
+	assert.Contains(t, dot, `"core-1" [label="core-1\nused", fillcolor=salmon];`)
+	assert.Contains(t, dot, `"core-2" [label="core-2\nfree", fillcolor=palegreen];`)
+	numa1 := dot[strings.Index(dot, `subgraph "cluster_numa-1"`):]
+	assert.Contains(t, numa1[:strings.Index(numa1, "}")], `"nic-ib0"`)
+	assert.Contains(t, dot, `"gpu-1" -> "nic-ib0" [dir=none, style=dotted];`)
+	assert.Contains(t, dot, `"numa-0" -> "numa-1" [dir=none, color=grey, label="2.1"];`)
+	assert.NotContains(t, dot, `"socket-0" -> "numa-0"`, "clusters imply what sockets contain")
+
+	out.Reset()
+	assert.NoError(t, graph.WriteDOT(&out))
+	assert.Equal(t, dot, out.String())
+}
+
+func TestWriteGraphML(t *testing.T) {
+	graph := newRoundTripGraph()
+	var out bytes.Buffer
+	assert.NoError(t, graph.WriteGraphML(&out))
+
+	var document graphML
+	assert.NoError(t, xml.Unmarshal(out.Bytes(), &document))
+	assert.Len(t, document.Graph.Nodes, len(graph.Nodes))
+	assert.Len(t, document.Graph.Edges, len(graph.Edges))
+
+	types := make(map[string]string)
+	for _, key := range document.Keys {
+		types[key.ID] = key.Type
+	}
+	assert.Equal(t, "long", types["node.memoryTotal"])
+	assert.Equal(t, "double", types["node.utilization"])
+	assert.Equal(t, "string", types["node.consumers"])
+
+	for _, node := range document.Graph.Nodes {
+		if node.ID != "core-1" {
+			continue
+		}
+		data := make(map[string]string)
+		for _, d := range node.Data {
+			data[d.Key] = d.Value
+		}
+		assert.Equal(t, "CPUCore", data["node.type"])
+		assert.Equal(t, "87.5", data["node.utilization"])
+		assert.True(t, json.Valid([]byte(data["node.consumers"])), "structured values are JSON")
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	graph := newRoundTripGraph()
+	var out bytes.Buffer
+	assert.NoError(t, graph.WriteJSON(&out))
+
+	var document Document
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &document))
+	assert.Equal(t, "core-0", document.Nodes[0].ID)
+	assert.Equal(t, "coregroup-0-0", document.Nodes[0].Parent)
+	assert.Equal(t, graph.Summary(), document.Summary)
+
+	// The export reads back as a topology
+	var topology crd.FlexTopoTopology
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &topology))
+	rebuilt, err := FromTopology(&topology)
+	assert.NoError(t, err)
+	assert.True(t, Diff(graph, rebuilt).Empty())
+}
@@ -1,13 +1,17 @@
 package graph
 
 import (
-	"flextopo/pkg/crd"
 	"flextopo/pkg/utils"
 	"fmt"
 	"sort"
+	"sync"
 )
 
-// FlexTopoGraph represents the entire topology graph
+// FlexTopoGraph represents the entire topology graph.
+// Nodes and Edges should be modified through AddNode and addEdge so that the indexes
+// stay consistent, otherwise Reindex must be called afterwards.
+// The methods of FlexTopoGraph are safe for concurrent use. Reading Nodes, Edges or the
+// returned nodes directly is only safe on a Snapshot, which is never modified.
 type FlexTopoGraph struct {
 	Nodes map[string]*Node // key: nodeID, value: node
 	// key: edgeID, value: edge
@@ -15,6 +19,15 @@ type FlexTopoGraph struct {
 	Edges map[string]*Edge
 	// CPU core group size, configurable
 	CoreGroupSize int
+
+	index *graphIndex
+	// coreOfCPU maps logical CPU numbers to core IDs, SMT siblings share a core
+	coreOfCPU map[int]int
+	// unmatchedUsage records usage of cores or GPUs that are not part of the graph
+	unmatchedUsage []unmatchedUsage
+	mu             sync.RWMutex
+	// frozen is set on snapshots, which must not be modified
+	frozen bool
 }
 
 // NewFlexTopoGraph creates a new instance of FlexTopoGraph
@@ -23,11 +36,17 @@ func NewFlexTopoGraph(coreGroupSize int) *FlexTopoGraph {
 		Nodes:         make(map[string]*Node),
 		Edges:         make(map[string]*Edge), // Initialize as an empty map
 		CoreGroupSize: coreGroupSize,
+		index:         newGraphIndex(),
+		coreOfCPU:     make(map[int]int),
 	}
 }
 
-// BuildCPUNodes builds nodes and edges based on CPU information
+// BuildCPUNodes builds nodes and edges based on CPU information. The CPUs must have a known
+// core, socket and NUMA node.
 func (g *FlexTopoGraph) BuildCPUNodes(cpuInfos []utils.CPUInfo) {
+	g.lock()
+	defer g.mu.Unlock()
+
 	// Sort CPUInfo by Socket, NUMA, CoreID
 	sort.Slice(cpuInfos, func(i, j int) bool {
 		if cpuInfos[i].SocketID != cpuInfos[j].SocketID {
@@ -55,144 +74,233 @@ func (g *FlexTopoGraph) BuildCPUNodes(cpuInfos []utils.CPUInfo) {
 		// Create or get Core Group node
 		coreGroupID := fmt.Sprintf("coregroup-%d-%d", cpuInfo.NumaNodeID, cpuInfo.CoreID/g.CoreGroupSize)
 		coreGroupNode := g.getNode(coreGroupID, "CoreGroup")
-		coreGroupNode.Attributes["nodeID"] = cpuInfo.NumaNodeID
-		coreGroupNode.Attributes["groupIndex"] = cpuInfo.CoreID / g.CoreGroupSize
+		coreGroupNode.CoreGroup().NodeID = cpuInfo.NumaNodeID
+		coreGroupNode.CoreGroup().GroupIndex = cpuInfo.CoreID / g.CoreGroupSize
 		g.addEdge(numaNode, coreGroupNode, "contains")
 
 		// Create CPU Core node
+		g.coreOfCPU[cpuInfo.CPUID] = cpuInfo.CoreID
 		coreNode := g.getNode(coreID, "CPUCore")
-		coreNode.Attributes["status"] = "free"
+		// A core is offline only if all of its CPUs are
+		if core := coreNode.CPUCore(); !cpuInfo.Offline {
+			core.Status = StatusFree
+		} else if core.Status == "" {
+			core.Status = StatusOffline
+		}
 		g.addEdge(coreGroupNode, coreNode, "contains")
 	}
 }
 
 // NewGPUNode creates a new GPU node
 func (g *FlexTopoGraph) NewGPUNode(index int, uuid, name string, memoryTotal int) *Node {
-	gpuID := fmt.Sprintf("gpu-%d", index)
-	gpuNode := &Node{
-		ID:   gpuID,
-		Type: "GPU",
-		Attributes: map[string]interface{}{
-			"uuid":        uuid,
-			"name":        name,
-			"memoryTotal": memoryTotal,
-			"status":      "free",
-		},
-	}
+	gpuNode := NewNode(fmt.Sprintf("gpu-%d", index), "GPU")
+	gpu := gpuNode.GPU()
+	gpu.UUID = uuid
+	gpu.Name = name
+	gpu.MemoryTotal = memoryTotal
+	gpu.Status = StatusFree
 	return gpuNode
 }
 
-// AddNode adds a node to the graph
+// NewNICNode creates a new NIC node
+func (g *FlexTopoGraph) NewNICNode(name, pciBusID string) *Node {
+	nicNode := NewNode(fmt.Sprintf("nic-%s", name), "NIC")
+	nic := nicNode.NIC()
+	nic.Name = name
+	nic.PCIBusID = pciBusID
+	nic.Status = StatusFree
+	return nicNode
+}
+
+// AddNode adds a node to the graph, replacing any node with the same ID. A node built as a
+// struct literal gets its typed attributes, taken from the schema attributes in Attributes.
 func (g *FlexTopoGraph) AddNode(node *Node) {
+	g.lock()
+	defer g.mu.Unlock()
+	g.addNode(node)
+}
+
+// addNode adds a node to the graph and its indexes
+func (g *FlexTopoGraph) addNode(node *Node) {
+	node.adoptAttributes()
+	if existing, exists := g.Nodes[node.ID]; exists {
+		g.index.removeNode(existing)
+	}
 	g.Nodes[node.ID] = node
+	g.index.addNode(node)
 }
 
-// UpdateCPUUsage updates the usage status of CPU Core nodes
-func (g *FlexTopoGraph) UpdateCPUUsage(podName string, cpuCores []int) {
-	for _, coreID := range cpuCores {
-		nodeID := fmt.Sprintf("core-%d", coreID)
-		if node, exists := g.Nodes[nodeID]; exists {
-			node.Attributes["status"] = "used"
-			node.Attributes["usedBy"] = podName
+// AttachToNUMA connects a device node to its local NUMA node with an "attached-to" edge,
+// it returns false if the NUMA node does not exist
+func (g *FlexTopoGraph) AttachToNUMA(node *Node, numaNodeID int) bool {
+	g.lock()
+	defer g.mu.Unlock()
+
+	numaNode, exists := g.Nodes[fmt.Sprintf("numa-%d", numaNodeID)]
+	if !exists {
+		return false
+	}
+	g.addEdge(node, numaNode, "attached-to")
+	return true
+}
+
+// Connect adds an edge of any type between two nodes, such as "nvlink" or "pcie-switch".
+// The weight scales the weight of the edge type in distance computations, 0 means 1.
+func (g *FlexTopoGraph) Connect(source, target *Node, edgeType string, weight float64) {
+	g.lock()
+	defer g.mu.Unlock()
+	g.addEdge(source, target, edgeType).Weight = weight
+}
+
+// SetNUMADistance connects two NUMA nodes with a "numa-distance" edge weighted by their
+// relative access distance, as reported by the firmware (10 is local), it returns false if
+// a NUMA node does not exist
+func (g *FlexTopoGraph) SetNUMADistance(from, to, distance int) bool {
+	g.lock()
+	defer g.mu.Unlock()
+
+	fromNode, fromExists := g.Nodes[fmt.Sprintf("numa-%d", from)]
+	toNode, toExists := g.Nodes[fmt.Sprintf("numa-%d", to)]
+	if !fromExists || !toExists {
+		return false
+	}
+	g.addEdge(fromNode, toNode, "numa-distance").Weight = float64(distance) / 10
+	return true
+}
+
+// SetNUMAMemory sets the total and free memory of a NUMA node in MiB
+func (g *FlexTopoGraph) SetNUMAMemory(numaNodeID, memoryTotal, memoryFree int) {
+	g.lock()
+	defer g.mu.Unlock()
+
+	if numaNode, exists := g.Nodes[fmt.Sprintf("numa-%d", numaNodeID)]; exists {
+		numaNode.NUMANode().MemoryTotal = memoryTotal
+		numaNode.NUMANode().MemoryFree = memoryFree
+	}
+}
+
+// UpdateCPUUsage updates the usage status of CPU Core nodes, given the logical CPUs of a
+// container. The consumer is returned with its memory placement classified against the
+// NUMA nodes of the given cores.
+func (g *FlexTopoGraph) UpdateCPUUsage(consumer Consumer, cpuCores []int) Consumer {
+	g.lock()
+	defer g.mu.Unlock()
+
+	localNUMA := make(map[int]bool)
+	var coreNodes []*Node
+	seen := make(map[*Node]bool)
+	for _, cpu := range cpuCores {
+		nodeID := fmt.Sprintf("core-%d", g.coreOf(cpu))
+		node, exists := g.Nodes[nodeID]
+		if !exists {
+			g.unmatchedUsage = append(g.unmatchedUsage, unmatchedUsage{pod: consumer.Pod, resource: nodeID})
+			continue
 		}
+		if seen[node] {
+			// Another SMT sibling of the same core
+			continue
+		}
+		seen[node] = true
+		coreNodes = append(coreNodes, node)
+		if numaNodeID, ok := coreNUMANode(node); ok {
+			localNUMA[numaNodeID] = true
+		}
+	}
+
+	consumer.classifyMemory(localNUMA)
+	for _, node := range coreNodes {
+		addConsumer(node, consumer)
 	}
+	return consumer
 }
 
 // UpdateGPUUsage updates the usage status of GPU nodes
-func (g *FlexTopoGraph) UpdateGPUUsage(podName string, gpuUUIDs []string) {
+func (g *FlexTopoGraph) UpdateGPUUsage(consumer Consumer, gpuUUIDs []string) {
+	g.lock()
+	defer g.mu.Unlock()
+
 	for _, uuid := range gpuUUIDs {
 		// Find the corresponding GPU node
-		for _, node := range g.Nodes {
-			if node.Type == "GPU" {
-				if node.Attributes["uuid"] == uuid {
-					node.Attributes["status"] = "used"
-					node.Attributes["usedBy"] = podName
-					break
-				}
-			}
+		if node := g.gpuByUUID(uuid); node != nil {
+			addConsumer(node, consumer)
+		} else {
+			g.unmatchedUsage = append(g.unmatchedUsage, unmatchedUsage{pod: consumer.Pod, resource: uuid})
 		}
 	}
 }
 
-// addEdge adds an edge to the graph and maintains the Children field of nodes
-func (g *FlexTopoGraph) addEdge(source, target *Node, edgeType string) {
+// gpuByUUID returns the GPU node with the given UUID, or nil if there is none
+func (g *FlexTopoGraph) gpuByUUID(uuid string) *Node {
+	if node := g.index.byUUID[uuid]; node != nil && node.Type == "GPU" {
+		return node
+	}
+	return nil
+}
+
+// addEdge adds an edge to the graph and maintains the Children field of nodes for "contains" edges.
+// It returns the new edge, or the existing one if the nodes are already connected.
+func (g *FlexTopoGraph) addEdge(source, target *Node, edgeType string) *Edge {
 	edgeKey := fmt.Sprintf("%s-%s-%s", source.ID, target.ID, edgeType)
-	if _, exists := g.Edges[edgeKey]; !exists {
-		edge := &Edge{
+	edge, exists := g.Edges[edgeKey]
+	if !exists {
+		edge = &Edge{
 			Source: source,
 			Target: target,
 			Type:   edgeType,
 		}
 		g.Edges[edgeKey] = edge
+		g.index.addEdge(edge)
 
-		// Maintain the Children field
-		source.Children = append(source.Children, target)
+		// Maintain the Children and Parent fields
+		if edgeType == "contains" {
+			source.Children = append(source.Children, target)
+			target.Parent = source
+		}
 	}
+	return edge
 }
 
-// ToSpec converts FlexTopoGraph to FlexTopoSpec
-func (g *FlexTopoGraph) ToSpec() *crd.FlexTopoSpec {
-	spec := &crd.FlexTopoSpec{
-		Nodes: []crd.FlexTopoNode{},
-		Edges: []crd.FlexTopoEdge{},
+// getNode gets or creates a node
+func (g *FlexTopoGraph) getNode(id, nodeType string) *Node {
+	if node, exists := g.Nodes[id]; exists {
+		return node
 	}
+	node := NewNode(id, nodeType)
+	node.Children = []*Node{}
+	g.addNode(node)
+	return node
+}
 
-	// Convert nodes
-	for _, node := range g.Nodes {
-		specNode := crd.FlexTopoNode{
-			ID:         node.ID,
-			Type:       node.Type,
-			Attributes: node.Attributes,
-		}
-		spec.Nodes = append(spec.Nodes, specNode)
+// coreOf returns the core ID of a logical CPU, CPUs unknown to the graph map to themselves
+func (g *FlexTopoGraph) coreOf(cpu int) int {
+	if coreID, ok := g.coreOfCPU[cpu]; ok {
+		return coreID
 	}
+	return cpu
+}
 
-	// Convert edges
-	for _, edge := range g.Edges {
-		specEdge := crd.FlexTopoEdge{
-			Source: edge.Source.ID,
-			Target: edge.Target.ID,
-			Type:   edge.Type,
-		}
-		spec.Edges = append(spec.Edges, specEdge)
+// coreNUMANode returns the ID of the NUMA node a CPU Core node belongs to
+func coreNUMANode(core *Node) (int, bool) {
+	if core.Parent == nil || core.Parent.CoreGroup() == nil {
+		return 0, false
 	}
-
-	return spec
+	return core.Parent.CoreGroup().NodeID, true
 }
 
-// getNode gets or creates a node
-func (g *FlexTopoGraph) getNode(id, nodeType string) *Node {
-	if node, exists := g.Nodes[id]; exists {
-		return node
+// lock acquires the write lock. Modifying a frozen snapshot is a programming error.
+func (g *FlexTopoGraph) lock() {
+	if g.frozen {
+		panic("graph: modification of a frozen FlexTopoGraph snapshot")
 	}
-	node := &Node{
-		ID:         id,
-		Type:       nodeType,
-		Attributes: make(map[string]interface{}),
-		Children:   []*Node{},
-	}
-	g.Nodes[id] = node
-	return node
+	g.mu.Lock()
 }
 
 // Helper function to get all nodes of a specific type
 func (g *FlexTopoGraph) getNodesByType(nodeType string) []*Node {
-	var nodes []*Node
-	for _, node := range g.Nodes {
-		if node.Type == nodeType {
-			nodes = append(nodes, node)
-		}
-	}
-	return nodes
+	return g.NodesByType(nodeType)
 }
 
 // Helper function to get edges of a specific type originating from a source node
 func (g *FlexTopoGraph) getEdges(source *Node, edgeType string) []*Edge {
-	var edges []*Edge
-	for _, edge := range g.Edges {
-		if edge.Source == source && edge.Type == edgeType {
-			edges = append(edges, edge)
-		}
-	}
-	return edges
+	return g.OutEdges(source, edgeType)
 }
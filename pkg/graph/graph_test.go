@@ -114,6 +114,89 @@ func TestBuildCPUNodes(t *testing.T) {
 	// Verify CPUCore attributes
 	for i := 0; i < 64; i++ {
 		cpuCore := graph.getNode(fmt.Sprintf("core-%d", i), "CPUCore")
-		assert.Equal(t, "free", cpuCore.Attributes["status"], "The status of each CPU Core should be free")
+		assert.Equal(t, "free", cpuCore.Status(), "The status of each CPU Core should be free")
 	}
 }
+
+func TestBuildCPUNodesOffline(t *testing.T) {
+	graph := NewFlexTopoGraph(8)
+	graph.BuildCPUNodes([]utils.CPUInfo{
+		{CPUID: 0, CoreID: 0, SocketID: 0, NumaNodeID: 0},
+		{CPUID: 1, CoreID: 1, SocketID: 0, NumaNodeID: 0, Offline: true},
+		// SMT siblings, the core stays online while one of its CPUs is
+		{CPUID: 2, CoreID: 2, SocketID: 0, NumaNodeID: 0, Offline: true},
+		{CPUID: 66, CoreID: 2, SocketID: 0, NumaNodeID: 0},
+	})
+
+	assert.Equal(t, StatusFree, graph.Nodes["core-0"].Status())
+	assert.Equal(t, StatusOffline, graph.Nodes["core-1"].Status())
+	assert.Equal(t, StatusFree, graph.Nodes["core-2"].Status())
+	graph.UpdateRollups()
+	assert.Equal(t, 3, graph.Summary().CoresTotal)
+	assert.Equal(t, 2, graph.Summary().CoresFree)
+}
+
+func TestUpdateUsage(t *testing.T) {
+	graph := NewFlexTopoGraph(8)
+	graph.BuildCPUNodes([]utils.CPUInfo{
+		{CPUID: 0, CoreID: 0, SocketID: 0, NumaNodeID: 0},
+		{CPUID: 1, CoreID: 1, SocketID: 0, NumaNodeID: 0},
+	})
+	graph.AddNode(graph.NewGPUNode(0, "GPU-aaaa", "NVIDIA GeForce RTX 4090", 24564))
+
+	main := Consumer{Pod: "pod-a", Namespace: "default", Container: "main", Kind: ContainerKindRegular}
+	sidecar := Consumer{Pod: "pod-a", Namespace: "default", Container: "proxy", Kind: ContainerKindSidecar}
+	graph.UpdateCPUUsage(main, []int{0})
+	graph.UpdateCPUUsage(sidecar, []int{0})
+	graph.UpdateGPUUsage(main, []string{"GPU-aaaa"})
+
+	core0 := graph.Nodes["core-0"]
+	assert.Equal(t, "used", core0.Status())
+	assert.Equal(t, "pod-a", core0.Usage().UsedBy)
+	assert.Equal(t, []Consumer{main, sidecar}, core0.Usage().Consumers)
+
+	core1 := graph.Nodes["core-1"]
+	assert.Equal(t, "free", core1.Status())
+	assert.Empty(t, core1.Usage().Consumers)
+
+	gpu0 := graph.Nodes["gpu-0"]
+	assert.Equal(t, "used", gpu0.Status())
+	assert.Equal(t, []Consumer{main}, gpu0.Usage().Consumers)
+}
+
+func TestUpdateCPUUsageSMT(t *testing.T) {
+	// CPUs 0 and 2 are the SMT siblings of core 0, 1 and 3 those of core 1
+	graph := NewFlexTopoGraph(2)
+	graph.BuildCPUNodes([]utils.CPUInfo{
+		{CPUID: 0, CoreID: 0}, {CPUID: 1, CoreID: 1}, {CPUID: 2, CoreID: 0}, {CPUID: 3, CoreID: 1},
+	})
+	graph.UpdateCPUUsage(Consumer{Pod: "pod-a"}, []int{2, 0})
+	graph.MarkReservedCPUs([]int{3})
+
+	assert.Equal(t, []Consumer{{Pod: "pod-a"}}, graph.Nodes["core-0"].Usage().Consumers)
+	assert.Equal(t, StatusReserved, graph.Nodes["core-1"].Status())
+	assert.NoError(t, graph.Validate())
+}
+
+func TestUpdateCPUUsageRemoteMemory(t *testing.T) {
+	graph := NewFlexTopoGraph(2)
+	graph.BuildCPUNodes([]utils.CPUInfo{
+		{CPUID: 0, CoreID: 0, SocketID: 0, NumaNodeID: 0},
+		{CPUID: 1, CoreID: 1, SocketID: 0, NumaNodeID: 0},
+		{CPUID: 2, CoreID: 2, SocketID: 1, NumaNodeID: 1},
+		{CPUID: 3, CoreID: 3, SocketID: 1, NumaNodeID: 1},
+	})
+
+	// Pinned to NUMA 0, but most memory lives on NUMA 1
+	remote := graph.UpdateCPUUsage(Consumer{Pod: "pod-a", NUMAMemory: map[int]int64{0: 1 << 20, 1: 3 << 20}}, []int{0, 1})
+	assert.True(t, remote.RemoteMemory)
+	assert.InDelta(t, 0.75, remote.RemoteMemoryRatio, 1e-9)
+
+	// Pinned to NUMA 1 with local memory
+	local := graph.UpdateCPUUsage(Consumer{Pod: "pod-b", NUMAMemory: map[int]int64{0: 1 << 20, 1: 3 << 20}}, []int{2, 3})
+	assert.False(t, local.RemoteMemory)
+	assert.InDelta(t, 0.25, local.RemoteMemoryRatio, 1e-9)
+
+	consumers := graph.Nodes["core-0"].Usage().Consumers
+	assert.True(t, consumers[0].RemoteMemory, "The recorded consumer should carry the remote memory flag")
+}
@@ -0,0 +1,160 @@
+package graph
+
+import "sort"
+
+// graphIndex holds lookup structures maintained alongside the Nodes and Edges maps, so
+// that lookups by type, UUID or PCI address and edge traversals do not scan the graph
+type graphIndex struct {
+	byType map[string]map[string]*Node // key: node type, then nodeID
+	byUUID map[string]*Node
+	byPCI  map[string]*Node
+	// out and in hold the edges leaving and entering a node, keyed by nodeID then edge type
+	out map[string]map[string][]*Edge
+	in  map[string]map[string][]*Edge
+}
+
+// newGraphIndex creates an empty graphIndex
+func newGraphIndex() *graphIndex {
+	return &graphIndex{
+		byType: make(map[string]map[string]*Node),
+		byUUID: make(map[string]*Node),
+		byPCI:  make(map[string]*Node),
+		out:    make(map[string]map[string][]*Edge),
+		in:     make(map[string]map[string][]*Edge),
+	}
+}
+
+// addNode indexes node. The "uuid" and "pciBusId" attributes are indexed at this point,
+// so they must be set before the node is added to the graph.
+func (idx *graphIndex) addNode(node *Node) {
+	if idx.byType[node.Type] == nil {
+		idx.byType[node.Type] = make(map[string]*Node)
+	}
+	idx.byType[node.Type][node.ID] = node
+	uuid, pciBusID := deviceIDs(node)
+	if uuid != "" {
+		idx.byUUID[uuid] = node
+	}
+	if pciBusID != "" {
+		idx.byPCI[pciBusID] = node
+	}
+}
+
+// removeNode drops node from the node lookups, its edges are left untouched
+func (idx *graphIndex) removeNode(node *Node) {
+	delete(idx.byType[node.Type], node.ID)
+	uuid, pciBusID := deviceIDs(node)
+	if idx.byUUID[uuid] == node {
+		delete(idx.byUUID, uuid)
+	}
+	if idx.byPCI[pciBusID] == node {
+		delete(idx.byPCI, pciBusID)
+	}
+}
+
+// deviceIDs returns the UUID and PCI bus ID of GPU and NIC nodes, empty if unknown
+func deviceIDs(node *Node) (string, string) {
+	if gpu := node.GPU(); gpu != nil {
+		return gpu.UUID, gpu.PCIBusID
+	}
+	if nic := node.NIC(); nic != nil {
+		return "", nic.PCIBusID
+	}
+	return "", ""
+}
+
+// addEdge indexes edge in the adjacency lists of both of its ends
+func (idx *graphIndex) addEdge(edge *Edge) {
+	if idx.out[edge.Source.ID] == nil {
+		idx.out[edge.Source.ID] = make(map[string][]*Edge)
+	}
+	idx.out[edge.Source.ID][edge.Type] = append(idx.out[edge.Source.ID][edge.Type], edge)
+	if idx.in[edge.Target.ID] == nil {
+		idx.in[edge.Target.ID] = make(map[string][]*Edge)
+	}
+	idx.in[edge.Target.ID][edge.Type] = append(idx.in[edge.Target.ID][edge.Type], edge)
+}
+
+// Reindex rebuilds all indexes, Parent and Children links from the Nodes and Edges maps.
+// It is only needed after modifying those maps or indexed attributes directly.
+func (g *FlexTopoGraph) Reindex() {
+	g.lock()
+	defer g.mu.Unlock()
+
+	for _, node := range g.Nodes {
+		node.Parent = nil
+		node.Children = []*Node{}
+	}
+	for _, key := range sortedEdgeKeys(g.Edges) {
+		edge := g.Edges[key]
+		if edge.Type == "contains" {
+			edge.Source.Children = append(edge.Source.Children, edge.Target)
+			edge.Target.Parent = edge.Source
+		}
+	}
+	g.rebuildIndex()
+}
+
+// rebuildIndex rebuilds the lookups and adjacency lists from the Nodes and Edges maps
+func (g *FlexTopoGraph) rebuildIndex() {
+	g.index = newGraphIndex()
+	for _, node := range g.Nodes {
+		g.index.addNode(node)
+	}
+	for _, key := range sortedEdgeKeys(g.Edges) {
+		g.index.addEdge(g.Edges[key])
+	}
+}
+
+// NodesByType returns all nodes of the given type, in natural ID order
+func (g *FlexTopoGraph) NodesByType(nodeType string) []*Node {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	nodes := make([]*Node, 0, len(g.index.byType[nodeType]))
+	for _, node := range g.index.byType[nodeType] {
+		nodes = append(nodes, node)
+	}
+	SortNodes(nodes)
+	return nodes
+}
+
+// NodeByUUID returns the node with the given "uuid" attribute, or nil if there is none
+func (g *FlexTopoGraph) NodeByUUID(uuid string) *Node {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.index.byUUID[uuid]
+}
+
+// NodeByPCIAddress returns the node with the given "pciBusId" attribute, or nil if there is none
+func (g *FlexTopoGraph) NodeByPCIAddress(pciBusID string) *Node {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.index.byPCI[pciBusID]
+}
+
+// OutEdges returns the edges of the given type leaving node
+func (g *FlexTopoGraph) OutEdges(node *Node, edgeType string) []*Edge {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return append([]*Edge(nil), g.index.out[node.ID][edgeType]...)
+}
+
+// InEdges returns the edges of the given type entering node
+func (g *FlexTopoGraph) InEdges(node *Node, edgeType string) []*Edge {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return append([]*Edge(nil), g.index.in[node.ID][edgeType]...)
+}
+
+// sortedEdgeKeys returns the keys of an edge map in natural order
+func sortedEdgeKeys(edges map[string]*Edge) []string {
+	keys := make([]string, 0, len(edges))
+	for key := range edges {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return NaturalLess(keys[i], keys[j])
+	})
+	return keys
+}
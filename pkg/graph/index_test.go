@@ -0,0 +1,144 @@
+package graph
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flextopo/pkg/utils"
+)
+
+// newLargeGraph builds a 2-socket, 8-NUMA machine with cpus CPU cores and gpus GPUs
+func newLargeGraph(cpus, gpus int) *FlexTopoGraph {
+	graph := NewFlexTopoGraph(8)
+	cpuInfos := make([]utils.CPUInfo, 0, cpus)
+	for cpu := 0; cpu < cpus; cpu++ {
+		numa := cpu * 8 / cpus
+		cpuInfos = append(cpuInfos, utils.CPUInfo{CPUID: cpu, CoreID: cpu, SocketID: numa / 4, NumaNodeID: numa})
+	}
+	graph.BuildCPUNodes(cpuInfos)
+	for index := 0; index < gpus; index++ {
+		gpu := graph.NewGPUNode(index, fmt.Sprintf("GPU-%04d", index), "NVIDIA H100", 81559)
+		gpu.GPU().PCIBusID = fmt.Sprintf("0000:%02x:00.0", index+1)
+		graph.AddNode(gpu)
+		graph.AttachToNUMA(gpu, index*8/gpus)
+	}
+	return graph
+}
+
+func TestIndexes(t *testing.T) {
+	graph := newLargeGraph(64, 8)
+
+	assert.Equal(t, 8, len(graph.NodesByType("GPU")))
+	assert.Equal(t, "core-2", graph.NodesByType("CPUCore")[2].ID, "NodesByType should return nodes in natural order")
+	assert.Equal(t, "gpu-3", graph.NodeByUUID("GPU-0003").ID)
+	assert.Equal(t, "gpu-4", graph.NodeByPCIAddress("0000:05:00.0").ID)
+	assert.Nil(t, graph.NodeByUUID("GPU-missing"))
+
+	core := graph.Nodes["core-9"]
+	assert.Equal(t, "coregroup-1-1", core.Parent.ID)
+	assert.Equal(t, "numa-1", core.Parent.Parent.ID)
+	assert.Nil(t, graph.Nodes["socket-0"].Parent)
+	assert.Equal(t, 1, len(graph.InEdges(core, "contains")))
+	assert.Equal(t, 1, len(graph.OutEdges(graph.Nodes["gpu-0"], "attached-to")))
+	assert.Equal(t, 1, len(graph.InEdges(graph.Nodes["numa-0"], "attached-to")))
+
+	// Replacing a node keeps the lookups consistent
+	replacement := graph.NewGPUNode(3, "GPU-new", "NVIDIA H100", 81559)
+	graph.AddNode(replacement)
+	assert.Nil(t, graph.NodeByUUID("GPU-0003"))
+	assert.Equal(t, replacement, graph.NodeByUUID("GPU-new"))
+	assert.Equal(t, 8, len(graph.NodesByType("GPU")))
+}
+
+func TestReindex(t *testing.T) {
+	graph := newLargeGraph(16, 2)
+	graph.Nodes["gpu-1"].GPU().UUID = "GPU-renamed"
+	graph.Reindex()
+
+	assert.Equal(t, "gpu-1", graph.NodeByUUID("GPU-renamed").ID)
+	assert.Equal(t, "coregroup-0-0", graph.Nodes["core-1"].Parent.ID)
+	assert.Equal(t, 4, len(graph.Nodes["socket-0"].Children))
+	assert.Equal(t, 2, len(graph.Nodes["coregroup-0-0"].Children))
+}
+
+// linearGPUByUUID is the lookup used before the indexes, kept as a benchmark baseline
+func linearGPUByUUID(graph *FlexTopoGraph, uuid string) *Node {
+	for _, node := range graph.Nodes {
+		if node.Type == "GPU" && node.GPU().UUID == uuid {
+			return node
+		}
+	}
+	return nil
+}
+
+// linearEdges is the edge lookup used before the indexes, kept as a benchmark baseline
+func linearEdges(graph *FlexTopoGraph, source *Node, edgeType string) []*Edge {
+	var edges []*Edge
+	for _, edge := range graph.Edges {
+		if edge.Source == source && edge.Type == edgeType {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+func BenchmarkGPUByUUID(b *testing.B) {
+	graph := newLargeGraph(256, 16)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		graph.gpuByUUID(fmt.Sprintf("GPU-%04d", i%16))
+	}
+}
+
+func BenchmarkGPUByUUIDLinear(b *testing.B) {
+	graph := newLargeGraph(256, 16)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		linearGPUByUUID(graph, fmt.Sprintf("GPU-%04d", i%16))
+	}
+}
+
+func BenchmarkGetEdges(b *testing.B) {
+	graph := newLargeGraph(256, 16)
+	coreGroups := graph.NodesByType("CoreGroup")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		graph.getEdges(coreGroups[i%len(coreGroups)], "contains")
+	}
+}
+
+func BenchmarkGetEdgesLinear(b *testing.B) {
+	graph := newLargeGraph(256, 16)
+	coreGroups := graph.NodesByType("CoreGroup")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		linearEdges(graph, coreGroups[i%len(coreGroups)], "contains")
+	}
+}
+
+// BenchmarkUsageCycle measures the usage passes of one collection cycle with one
+// consumer per core and per GPU
+func BenchmarkUsageCycle(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		graph := newLargeGraph(256, 16)
+		b.StartTimer()
+		for core := 0; core < 256; core++ {
+			graph.UpdateCPUUsage(Consumer{Pod: fmt.Sprintf("pod-%d", core)}, []int{core})
+		}
+		for gpu := 0; gpu < 16; gpu++ {
+			graph.UpdateGPUUsage(Consumer{Pod: fmt.Sprintf("pod-%d", gpu)}, []string{fmt.Sprintf("GPU-%04d", gpu)})
+		}
+	}
+}
+
+func BenchmarkAncestors(b *testing.B) {
+	graph := newLargeGraph(256, 16)
+	cores := graph.NodesByType("CPUCore")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		graph.Ancestors(cores[i%len(cores)])
+	}
+}
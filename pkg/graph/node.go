@@ -1,10 +1,260 @@
 package graph
 
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
 // Node represents a node in the topology graph
 type Node struct {
-	ID         string
-	Type       string
+	ID   string
+	Type string
+	// Attributes holds the attributes outside the registered schema of the node type, such as
+	// "nvidia.com/migMode". Attributes in the schema are stored in the typed attributes, see
+	// CPUCore, GPU and the other typed accessors.
 	Attributes map[string]interface{}
 	// New field
 	Children []*Node // List of child nodes, used to represent hierarchical structure
+	Parent   *Node   // Node containing this node through a "contains" edge, nil for roots
+
+	// typed points to the typed attribute struct registered for Type, e.g. *GPUAttributes
+	typed interface{}
+}
+
+// NewNode creates a node with empty typed attributes for its type
+func NewNode(id, nodeType string) *Node {
+	node := &Node{ID: id, Type: nodeType, Attributes: make(map[string]interface{})}
+	node.adoptAttributes()
+	return node
+}
+
+// adoptAttributes creates the typed attributes of a node built as a struct literal and moves
+// the schema attributes set in Attributes into them. Values that do not convert to their
+// attribute type stay in Attributes, where validation reports them.
+func (n *Node) adoptAttributes() {
+	registered, ok := registry[n.Type]
+	if !ok || n.typed != nil {
+		return
+	}
+	n.typed = reflect.New(registered.structType).Interface()
+	for name, value := range n.Attributes {
+		if _, known := registered.fields[name]; known && n.SetAttribute(name, value) == nil {
+			delete(n.Attributes, name)
+		}
+	}
+}
+
+// TypedAttributes returns a pointer to the typed attribute struct of the node, e.g.
+// *GPUAttributes for a GPU, or nil if the node type is not registered
+func (n *Node) TypedAttributes() interface{} {
+	return n.typed
+}
+
+// Socket returns the typed attributes of a Socket node, or nil for other types
+func (n *Node) Socket() *SocketAttributes {
+	attributes, _ := n.typed.(*SocketAttributes)
+	return attributes
+}
+
+// NUMANode returns the typed attributes of a NUMANode node, or nil for other types
+func (n *Node) NUMANode() *NUMANodeAttributes {
+	attributes, _ := n.typed.(*NUMANodeAttributes)
+	return attributes
+}
+
+// CoreGroup returns the typed attributes of a CoreGroup node, or nil for other types
+func (n *Node) CoreGroup() *CoreGroupAttributes {
+	attributes, _ := n.typed.(*CoreGroupAttributes)
+	return attributes
+}
+
+// CPUCore returns the typed attributes of a CPUCore node, or nil for other types
+func (n *Node) CPUCore() *CPUCoreAttributes {
+	attributes, _ := n.typed.(*CPUCoreAttributes)
+	return attributes
+}
+
+// GPU returns the typed attributes of a GPU node, or nil for other types
+func (n *Node) GPU() *GPUAttributes {
+	attributes, _ := n.typed.(*GPUAttributes)
+	return attributes
+}
+
+// NIC returns the typed attributes of a NIC node, or nil for other types
+func (n *Node) NIC() *NICAttributes {
+	attributes, _ := n.typed.(*NICAttributes)
+	return attributes
+}
+
+// Usage returns the allocation attributes of CPUCore, GPU and NIC nodes, or nil for other types
+func (n *Node) Usage() *Usage {
+	switch attributes := n.typed.(type) {
+	case *CPUCoreAttributes:
+		return &attributes.Usage
+	case *GPUAttributes:
+		return &attributes.Usage
+	case *NICAttributes:
+		return &attributes.Usage
+	}
+	return nil
+}
+
+// Status returns the status of CPUCore, GPU and NIC nodes, or "" for other types
+func (n *Node) Status() string {
+	if usage := n.Usage(); usage != nil {
+		return usage.Status
+	}
+	return ""
+}
+
+// coreCounts returns the core rollups of Socket, NUMANode and CoreGroup nodes, or nil
+func (n *Node) coreCounts() *CoreCounts {
+	switch attributes := n.typed.(type) {
+	case *SocketAttributes:
+		return &attributes.CoreCounts
+	case *NUMANodeAttributes:
+		return &attributes.CoreCounts
+	case *CoreGroupAttributes:
+		return &attributes.CoreCounts
+	}
+	return nil
+}
+
+// gpuCounts returns the GPU rollups of Socket and NUMANode nodes, or nil
+func (n *Node) gpuCounts() *GPUCounts {
+	switch attributes := n.typed.(type) {
+	case *SocketAttributes:
+		return &attributes.GPUCounts
+	case *NUMANodeAttributes:
+		return &attributes.GPUCounts
+	}
+	return nil
+}
+
+// Attribute returns the value of an attribute by name, typed or not. Like in the JSON form of
+// a node, optional typed attributes are absent while they hold their zero value.
+func (n *Node) Attribute(name string) (interface{}, bool) {
+	if registered := n.registration(); registered != nil {
+		if index, known := registered.fields[name]; known {
+			field := reflect.ValueOf(n.typed).Elem().FieldByIndex(index)
+			if !registered.schema[name].Required && isEmptyValue(field) {
+				return nil, false
+			}
+			return field.Interface(), true
+		}
+	}
+	value, exists := n.Attributes[name]
+	return value, exists
+}
+
+// AttributeMap returns the typed attributes and Attributes of the node in one map, with the
+// names and presence of the JSON form. Values are not copied.
+func (n *Node) AttributeMap() map[string]interface{} {
+	attributes := make(map[string]interface{}, len(n.Attributes)+8)
+	for name, value := range n.Attributes {
+		attributes[name] = value
+	}
+	if registered := n.registration(); registered != nil {
+		for _, name := range registered.names {
+			if value, present := n.Attribute(name); present {
+				attributes[name] = value
+			}
+		}
+	}
+	return attributes
+}
+
+// SetAttribute sets an attribute by name. Attributes in the schema of the node type are
+// stored in the typed attributes, values decoded from JSON are converted to their type.
+// Other attributes are stored in Attributes.
+func (n *Node) SetAttribute(name string, value interface{}) error {
+	if registered := n.registration(); registered != nil {
+		if index, known := registered.fields[name]; known {
+			field := reflect.ValueOf(n.typed).Elem().FieldByIndex(index)
+			converted, err := registered.schema[name].convert(value, field.Type())
+			if err != nil {
+				return fmt.Errorf("attribute %q %v", name, err)
+			}
+			field.Set(converted)
+			return nil
+		}
+	}
+	if n.Attributes == nil {
+		n.Attributes = make(map[string]interface{})
+	}
+	n.Attributes[name] = value
+	return nil
+}
+
+// DeleteAttribute removes an attribute by name, typed attributes are reset to their zero value
+func (n *Node) DeleteAttribute(name string) {
+	if registered := n.registration(); registered != nil {
+		if index, known := registered.fields[name]; known {
+			field := reflect.ValueOf(n.typed).Elem().FieldByIndex(index)
+			field.Set(reflect.Zero(field.Type()))
+			return
+		}
+	}
+	delete(n.Attributes, name)
+}
+
+// deepCopyTyped copies the typed attributes of the node including their slices
+func (n *Node) deepCopyTyped() interface{} {
+	registered := n.registration()
+	if registered == nil {
+		return nil
+	}
+	typedCopy := reflect.New(registered.structType)
+	typedCopy.Elem().Set(reflect.ValueOf(n.typed).Elem())
+	for _, index := range registered.fields {
+		field := typedCopy.Elem().FieldByIndex(index)
+		if field.Kind() == reflect.Slice && !field.IsNil() {
+			field.Set(reflect.ValueOf(deepCopyValue(field.Interface())))
+		}
+	}
+	return typedCopy.Interface()
+}
+
+// isEmptyValue reports whether a typed attribute is left out of the JSON form by omitempty
+func isEmptyValue(value reflect.Value) bool {
+	if value.Kind() == reflect.Slice {
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
+
+// convert returns value as the Go type of a typed attribute. Numbers decoded from JSON are
+// float64 and structured values generic maps and slices, these are converted.
+func (a AttributeSchema) convert(value interface{}, fieldType reflect.Type) (reflect.Value, error) {
+	if value != nil && reflect.TypeOf(value) == fieldType {
+		return reflect.ValueOf(value), nil
+	}
+	switch a.Kind {
+	case KindInt:
+		switch v := value.(type) {
+		case int64:
+			return reflect.ValueOf(int(v)), nil
+		case float64:
+			if v == math.Trunc(v) {
+				return reflect.ValueOf(int(v)), nil
+			}
+		}
+	case KindFloat:
+		switch v := value.(type) {
+		case int:
+			return reflect.ValueOf(float64(v)), nil
+		case int64:
+			return reflect.ValueOf(float64(v)), nil
+		}
+	case KindConsumers, KindGPUProcesses:
+		if _, generic := value.([]interface{}); generic || value == nil {
+			converted := reflect.New(fieldType)
+			if err := reencode(value, converted.Interface()); err != nil {
+				return reflect.Value{}, err
+			}
+			return converted.Elem(), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("has type %T, expected %s", value, a.Kind)
 }
@@ -0,0 +1,433 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// hierarchyEdgeTypes are the edge types that form the device hierarchy. For "contains"
+// the source is the parent, for "attached-to" the target is the parent.
+var hierarchyEdgeTypes = map[string]bool{
+	"contains":    true,
+	"attached-to": false,
+}
+
+// Predicate reports whether a node matches a condition
+type Predicate func(node *Node) bool
+
+// OfType matches nodes of the given type, "*" matches every type
+func OfType(nodeType string) Predicate {
+	return func(node *Node) bool {
+		return nodeType == "*" || node.Type == nodeType
+	}
+}
+
+// HasAttribute matches nodes whose attribute key is set, whatever its value
+func HasAttribute(key string) Predicate {
+	return func(node *Node) bool {
+		_, exists := node.Attribute(key)
+		return exists
+	}
+}
+
+// AttributeEquals matches nodes whose attribute key formats as value
+func AttributeEquals(key, value string) Predicate {
+	return func(node *Node) bool {
+		attribute, exists := node.Attribute(key)
+		return exists && fmt.Sprint(attribute) == value
+	}
+}
+
+// AttributeNotEquals matches nodes whose attribute key is unset or does not format as value
+func AttributeNotEquals(key, value string) Predicate {
+	return func(node *Node) bool {
+		return !AttributeEquals(key, value)(node)
+	}
+}
+
+// And matches nodes that match all predicates
+func And(predicates ...Predicate) Predicate {
+	return func(node *Node) bool {
+		for _, predicate := range predicates {
+			if !predicate(node) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Select returns the nodes of the graph matching all predicates, in natural ID order
+func (g *FlexTopoGraph) Select(predicates ...Predicate) []*Node {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return filterNodes(mapValues(g.Nodes), predicates...)
+}
+
+// Parents returns the direct parents of node along the given hierarchy edge types,
+// "contains" and "attached-to" if none are given
+func (g *FlexTopoGraph) Parents(node *Node, edgeTypes ...string) []*Node {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.parents(node, edgeTypes...)
+}
+
+// ChildrenOf returns the direct children of node along the given hierarchy edge types,
+// "contains" and "attached-to" if none are given
+func (g *FlexTopoGraph) ChildrenOf(node *Node, edgeTypes ...string) []*Node {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.childrenOf(node, edgeTypes...)
+}
+
+// Ancestors returns all nodes above node along the given hierarchy edge types
+func (g *FlexTopoGraph) Ancestors(node *Node, edgeTypes ...string) []*Node {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.ancestors(node, edgeTypes...)
+}
+
+// Descendants returns all nodes below node along the given hierarchy edge types
+func (g *FlexTopoGraph) Descendants(node *Node, edgeTypes ...string) []*Node {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.descendants(node, edgeTypes...)
+}
+
+// Siblings returns the other children of the parents of node along the given hierarchy edge types
+func (g *FlexTopoGraph) Siblings(node *Node, edgeTypes ...string) []*Node {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.siblings(node, edgeTypes...)
+}
+
+// NUMANodeOf returns the NUMA node that node is part of or attached to, or nil if there is none
+func (g *FlexTopoGraph) NUMANodeOf(node *Node) *Node {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.numaNodeOf(node)
+}
+
+// SameNUMA returns the nodes within the same NUMA node as node, excluding node itself
+func (g *FlexTopoGraph) SameNUMA(node *Node) []*Node {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.sameNUMA(node)
+}
+
+// parents implements Parents without locking
+func (g *FlexTopoGraph) parents(node *Node, edgeTypes ...string) []*Node {
+	var parents []*Node
+	for edgeType, parentIsSource := range hierarchyEdgeTypes {
+		if !matchesEdgeType(edgeType, edgeTypes) {
+			continue
+		}
+		if parentIsSource {
+			for _, edge := range g.index.in[node.ID][edgeType] {
+				parents = append(parents, edge.Source)
+			}
+		} else {
+			for _, edge := range g.index.out[node.ID][edgeType] {
+				parents = append(parents, edge.Target)
+			}
+		}
+	}
+	SortNodes(parents)
+	return parents
+}
+
+// childrenOf implements ChildrenOf without locking
+func (g *FlexTopoGraph) childrenOf(node *Node, edgeTypes ...string) []*Node {
+	var children []*Node
+	for edgeType, parentIsSource := range hierarchyEdgeTypes {
+		if !matchesEdgeType(edgeType, edgeTypes) {
+			continue
+		}
+		if parentIsSource {
+			for _, edge := range g.index.out[node.ID][edgeType] {
+				children = append(children, edge.Target)
+			}
+		} else {
+			for _, edge := range g.index.in[node.ID][edgeType] {
+				children = append(children, edge.Source)
+			}
+		}
+	}
+	SortNodes(children)
+	return children
+}
+
+// ancestors implements Ancestors without locking
+func (g *FlexTopoGraph) ancestors(node *Node, edgeTypes ...string) []*Node {
+	return walk(node, func(n *Node) []*Node { return g.parents(n, edgeTypes...) })
+}
+
+// descendants implements Descendants without locking
+func (g *FlexTopoGraph) descendants(node *Node, edgeTypes ...string) []*Node {
+	return walk(node, func(n *Node) []*Node { return g.childrenOf(n, edgeTypes...) })
+}
+
+// siblings implements Siblings without locking
+func (g *FlexTopoGraph) siblings(node *Node, edgeTypes ...string) []*Node {
+	seen := map[*Node]bool{node: true}
+	var siblings []*Node
+	for _, parent := range g.parents(node, edgeTypes...) {
+		for _, child := range g.childrenOf(parent, edgeTypes...) {
+			if !seen[child] {
+				seen[child] = true
+				siblings = append(siblings, child)
+			}
+		}
+	}
+	SortNodes(siblings)
+	return siblings
+}
+
+// numaNodeOf implements NUMANodeOf without locking
+func (g *FlexTopoGraph) numaNodeOf(node *Node) *Node {
+	if node.Type == "NUMANode" {
+		return node
+	}
+	for _, ancestor := range g.ancestors(node) {
+		if ancestor.Type == "NUMANode" {
+			return ancestor
+		}
+	}
+	return nil
+}
+
+// sameNUMA implements SameNUMA without locking
+func (g *FlexTopoGraph) sameNUMA(node *Node) []*Node {
+	numaNode := g.numaNodeOf(node)
+	if numaNode == nil {
+		return nil
+	}
+	var nodes []*Node
+	if numaNode != node {
+		nodes = append(nodes, numaNode)
+	}
+	for _, descendant := range g.descendants(numaNode) {
+		if descendant != node {
+			nodes = append(nodes, descendant)
+		}
+	}
+	SortNodes(nodes)
+	return nodes
+}
+
+// walk collects every node reachable from start through next, in natural ID order
+func walk(start *Node, next func(*Node) []*Node) []*Node {
+	seen := map[*Node]bool{start: true}
+	queue := []*Node{start}
+	var nodes []*Node
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, neighbor := range next(current) {
+			if seen[neighbor] {
+				continue
+			}
+			seen[neighbor] = true
+			nodes = append(nodes, neighbor)
+			queue = append(queue, neighbor)
+		}
+	}
+	SortNodes(nodes)
+	return nodes
+}
+
+// Query evaluates a textual selector against the graph. A selector is
+//
+//	[axis(nodeID)] type[predicate,...]
+//
+// where type is a node type or "*", each predicate is key=value, key!=value or key,
+// and axis is one of parents, children, ancestors, descendants, siblings or samenuma.
+// For example "samenuma(gpu-3) CPUCore[status=free]" selects the free cores local to gpu-3.
+func (g *FlexTopoGraph) Query(selector string) ([]*Node, error) {
+	parsed, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.Evaluate(g)
+}
+
+// Selector is a parsed textual selector, see FlexTopoGraph.Query
+type Selector struct {
+	// Axis restricts the candidates to nodes related to AxisNode, empty for the whole graph
+	Axis       string
+	AxisNode   string
+	Type       string
+	Predicates []Predicate
+}
+
+// selectorAxes maps axis names to the graph traversal they select
+var selectorAxes = map[string]func(g *FlexTopoGraph, node *Node) []*Node{
+	"parents":     func(g *FlexTopoGraph, node *Node) []*Node { return g.parents(node) },
+	"children":    func(g *FlexTopoGraph, node *Node) []*Node { return g.childrenOf(node) },
+	"ancestors":   func(g *FlexTopoGraph, node *Node) []*Node { return g.ancestors(node) },
+	"descendants": func(g *FlexTopoGraph, node *Node) []*Node { return g.descendants(node) },
+	"siblings":    func(g *FlexTopoGraph, node *Node) []*Node { return g.siblings(node) },
+	"samenuma":    func(g *FlexTopoGraph, node *Node) []*Node { return g.sameNUMA(node) },
+}
+
+// ParseSelector parses a textual selector, see FlexTopoGraph.Query for the syntax
+func ParseSelector(selector string) (*Selector, error) {
+	parsed := &Selector{}
+	rest := strings.TrimSpace(selector)
+
+	// Optional axis
+	if open := strings.Index(rest, "("); open >= 0 && !strings.Contains(rest[:open], "[") {
+		closing := strings.Index(rest, ")")
+		if closing < open {
+			return nil, fmt.Errorf("invalid selector %q: unterminated axis", selector)
+		}
+		parsed.Axis = strings.TrimSpace(rest[:open])
+		parsed.AxisNode = strings.TrimSpace(rest[open+1 : closing])
+		if _, ok := selectorAxes[parsed.Axis]; !ok {
+			return nil, fmt.Errorf("invalid selector %q: unknown axis %q", selector, parsed.Axis)
+		}
+		if parsed.AxisNode == "" {
+			return nil, fmt.Errorf("invalid selector %q: missing node in axis", selector)
+		}
+		rest = strings.TrimSpace(rest[closing+1:])
+	}
+
+	// Node type and optional predicates
+	parsed.Type = rest
+	if open := strings.Index(rest, "["); open >= 0 {
+		if !strings.HasSuffix(rest, "]") {
+			return nil, fmt.Errorf("invalid selector %q: unterminated predicate list", selector)
+		}
+		parsed.Type = strings.TrimSpace(rest[:open])
+		for _, term := range strings.Split(rest[open+1:len(rest)-1], ",") {
+			predicate, err := parsePredicate(strings.TrimSpace(term))
+			if err != nil {
+				return nil, fmt.Errorf("invalid selector %q: %v", selector, err)
+			}
+			parsed.Predicates = append(parsed.Predicates, predicate)
+		}
+	}
+	if parsed.Type == "" {
+		parsed.Type = "*"
+	}
+	if strings.ContainsFunc(parsed.Type, unicode.IsSpace) {
+		return nil, fmt.Errorf("invalid selector %q: invalid node type %q", selector, parsed.Type)
+	}
+	return parsed, nil
+}
+
+// parsePredicate parses a single key=value, key!=value or key term
+func parsePredicate(term string) (Predicate, error) {
+	if key, value, found := strings.Cut(term, "!="); found {
+		if key = strings.TrimSpace(key); key == "" {
+			return nil, fmt.Errorf("missing attribute in %q", term)
+		}
+		return AttributeNotEquals(key, strings.TrimSpace(value)), nil
+	}
+	if key, value, found := strings.Cut(term, "="); found {
+		if key = strings.TrimSpace(key); key == "" {
+			return nil, fmt.Errorf("missing attribute in %q", term)
+		}
+		return AttributeEquals(key, strings.TrimSpace(value)), nil
+	}
+	if term == "" {
+		return nil, fmt.Errorf("empty predicate")
+	}
+	return HasAttribute(term), nil
+}
+
+// Evaluate returns the nodes of g selected by s, in natural ID order
+func (s *Selector) Evaluate(g *FlexTopoGraph) ([]*Node, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	candidates := mapValues(g.Nodes)
+	if s.Axis != "" {
+		axisNode, exists := g.Nodes[s.AxisNode]
+		if !exists {
+			return nil, fmt.Errorf("node %s not found", s.AxisNode)
+		}
+		candidates = selectorAxes[s.Axis](g, axisNode)
+	}
+	predicates := append([]Predicate{OfType(s.Type)}, s.Predicates...)
+	return filterNodes(candidates, predicates...), nil
+}
+
+// filterNodes returns the nodes matching all predicates, in natural ID order
+func filterNodes(nodes []*Node, predicates ...Predicate) []*Node {
+	match := And(predicates...)
+	var selected []*Node
+	for _, node := range nodes {
+		if match(node) {
+			selected = append(selected, node)
+		}
+	}
+	SortNodes(selected)
+	return selected
+}
+
+// matchesEdgeType reports whether edgeType is a hierarchy edge type listed in edgeTypes,
+// or any hierarchy edge type if edgeTypes is empty
+func matchesEdgeType(edgeType string, edgeTypes []string) bool {
+	if _, ok := hierarchyEdgeTypes[edgeType]; !ok {
+		return false
+	}
+	if len(edgeTypes) == 0 {
+		return true
+	}
+	for _, t := range edgeTypes {
+		if t == edgeType {
+			return true
+		}
+	}
+	return false
+}
+
+// mapValues returns the nodes of a node map
+func mapValues(nodes map[string]*Node) []*Node {
+	values := make([]*Node, 0, len(nodes))
+	for _, node := range nodes {
+		values = append(values, node)
+	}
+	return values
+}
+
+// SortNodes sorts nodes by ID in natural order, so that "core-2" comes before "core-10"
+func SortNodes(nodes []*Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return NaturalLess(nodes[i].ID, nodes[j].ID)
+	})
+}
+
+// NaturalLess compares two strings treating runs of digits as numbers
+func NaturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		aDigits, bDigits := leadingDigits(a), leadingDigits(b)
+		if aDigits != "" && bDigits != "" {
+			aNum, _ := strconv.Atoi(aDigits)
+			bNum, _ := strconv.Atoi(bDigits)
+			if aNum != bNum {
+				return aNum < bNum
+			}
+			a, b = a[len(aDigits):], b[len(bDigits):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+// leadingDigits returns the run of ASCII digits at the start of s
+func leadingDigits(s string) string {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	return s[:end]
+}
@@ -0,0 +1,113 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flextopo/pkg/utils"
+)
+
+// newQueryTestGraph builds 2 sockets with one NUMA node each, 4 cores per NUMA node in
+// core groups of 2, and one GPU attached to each NUMA node
+func newQueryTestGraph() *FlexTopoGraph {
+	graph := NewFlexTopoGraph(2)
+	var cpuInfos []utils.CPUInfo
+	for cpu := 0; cpu < 8; cpu++ {
+		cpuInfos = append(cpuInfos, utils.CPUInfo{CPUID: cpu, CoreID: cpu, SocketID: cpu / 4, NumaNodeID: cpu / 4})
+	}
+	graph.BuildCPUNodes(cpuInfos)
+	for index := 0; index < 2; index++ {
+		gpu := graph.NewGPUNode(index, "GPU-"+string(rune('a'+index)), "NVIDIA GeForce RTX 4090", 24564)
+		graph.AddNode(gpu)
+		graph.AttachToNUMA(gpu, index)
+	}
+	graph.UpdateCPUUsage(Consumer{Pod: "pod-a"}, []int{0, 5})
+	return graph
+}
+
+func ids(nodes []*Node) []string {
+	result := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		result = append(result, node.ID)
+	}
+	return result
+}
+
+func TestSelect(t *testing.T) {
+	graph := newQueryTestGraph()
+
+	assert.Equal(t, []string{"core-1", "core-2", "core-3", "core-4", "core-6", "core-7"},
+		ids(graph.Select(OfType("CPUCore"), AttributeEquals("status", "free"))))
+	assert.Equal(t, []string{"core-0", "core-5"},
+		ids(graph.Select(OfType("CPUCore"), HasAttribute("usedBy"))))
+	assert.Equal(t, []string{"coregroup-1-2"},
+		ids(graph.Select(OfType("CoreGroup"), AttributeEquals("groupIndex", "2"))))
+}
+
+func TestTraversal(t *testing.T) {
+	graph := newQueryTestGraph()
+	core5 := graph.Nodes["core-5"]
+	gpu1 := graph.Nodes["gpu-1"]
+	numa0 := graph.Nodes["numa-0"]
+
+	assert.Equal(t, []string{"coregroup-1-2"}, ids(graph.Parents(core5)))
+	assert.Equal(t, []string{"coregroup-1-2", "numa-1", "socket-1"}, ids(graph.Ancestors(core5)))
+	assert.Equal(t, []string{"numa-1", "socket-1"}, ids(graph.Ancestors(gpu1)))
+	assert.Empty(t, graph.Ancestors(gpu1, "contains"))
+	assert.Equal(t, []string{"core-4"}, ids(graph.Siblings(core5)))
+	assert.Equal(t, []string{"core-0", "core-1", "core-2", "core-3", "coregroup-0-0", "coregroup-0-1", "gpu-0"},
+		ids(graph.Descendants(numa0)))
+	assert.Equal(t, []string{"core-0", "core-1", "core-2", "core-3", "coregroup-0-0", "coregroup-0-1"},
+		ids(graph.Descendants(numa0, "contains")))
+
+	assert.Equal(t, "numa-1", graph.NUMANodeOf(gpu1).ID)
+	assert.Equal(t, []string{"core-4", "core-5", "core-6", "core-7", "coregroup-1-2", "coregroup-1-3", "numa-1"},
+		ids(graph.SameNUMA(gpu1)))
+}
+
+func TestQuery(t *testing.T) {
+	graph := newQueryTestGraph()
+
+	tests := []struct {
+		selector string
+		want     []string
+		wantErr  bool
+	}{
+		{selector: "GPU", want: []string{"gpu-0", "gpu-1"}},
+		{selector: "samenuma(gpu-1) CPUCore[status=free]", want: []string{"core-4", "core-6", "core-7"}},
+		{selector: "ancestors(core-2) *", want: []string{"coregroup-0-1", "numa-0", "socket-0"}},
+		{selector: "children(numa-0) GPU", want: []string{"gpu-0"}},
+		{selector: "CPUCore[status!=free, usedBy=pod-a]", want: []string{"core-0", "core-5"}},
+		{selector: "CPUCore[usedBy]", want: []string{"core-0", "core-5"}},
+		{selector: "siblings(numa-0) NUMANode", want: nil},
+		{selector: "cousins(core-0) CPUCore", wantErr: true},
+		{selector: "descendants(numa-9) CPUCore", wantErr: true},
+		{selector: "CPUCore[status=free", wantErr: true},
+		{selector: "CPUCore[=free]", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			nodes, err := graph.Query(tt.selector)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.want == nil {
+				assert.Empty(t, nodes)
+				return
+			}
+			assert.Equal(t, tt.want, ids(nodes))
+		})
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	assert.True(t, NaturalLess("core-2", "core-10"))
+	assert.False(t, NaturalLess("core-10", "core-2"))
+	assert.True(t, NaturalLess("coregroup-0-9", "coregroup-0-10"))
+	assert.True(t, NaturalLess("core-1", "coregroup-0-0"))
+	assert.False(t, NaturalLess("gpu-1", "gpu-1"))
+}
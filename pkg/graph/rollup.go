@@ -0,0 +1,160 @@
+package graph
+
+import "fmt"
+
+// Capacity counts the cores and GPUs below an aggregate node by status
+type Capacity struct {
+	CoresTotal    int `json:"coresTotal"`
+	CoresFree     int `json:"coresFree"`
+	CoresUsed     int `json:"coresUsed"`
+	CoresReserved int `json:"coresReserved"`
+	GPUsTotal     int `json:"gpusTotal"`
+	GPUsFree      int `json:"gpusFree"`
+	GPUsUsed      int `json:"gpusUsed"`
+	GPUsReserved  int `json:"gpusReserved"`
+	// MemoryFree is the free memory in MiB, -1 if no NUMA node reports its memory
+	MemoryFree int `json:"memoryFree"`
+}
+
+// countCore adds a CPU Core node to the capacity
+func (c *Capacity) countCore(core *Node) {
+	c.CoresTotal++
+	switch core.Status() {
+	case StatusFree:
+		c.CoresFree++
+	case StatusUsed:
+		c.CoresUsed++
+	case StatusReserved:
+		c.CoresReserved++
+	}
+}
+
+// countGPU adds a GPU node to the capacity
+func (c *Capacity) countGPU(gpu *Node) {
+	c.GPUsTotal++
+	switch gpu.Status() {
+	case StatusFree:
+		c.GPUsFree++
+	case StatusUsed:
+		c.GPUsUsed++
+	case StatusReserved:
+		c.GPUsReserved++
+	}
+}
+
+// addMemory adds the free memory of a NUMA node to the capacity
+func (c *Capacity) addMemory(numaNode *Node) {
+	numa := numaNode.NUMANode()
+	if numa == nil || !numa.MemoryReported() {
+		return
+	}
+	if c.MemoryFree < 0 {
+		c.MemoryFree = 0
+	}
+	c.MemoryFree += numa.MemoryFree
+}
+
+// add merges the counts of other into c
+func (c *Capacity) add(other Capacity) {
+	c.CoresTotal += other.CoresTotal
+	c.CoresFree += other.CoresFree
+	c.CoresUsed += other.CoresUsed
+	c.CoresReserved += other.CoresReserved
+	c.GPUsTotal += other.GPUsTotal
+	c.GPUsFree += other.GPUsFree
+	c.GPUsUsed += other.GPUsUsed
+	c.GPUsReserved += other.GPUsReserved
+	if other.MemoryFree >= 0 {
+		if c.MemoryFree < 0 {
+			c.MemoryFree = 0
+		}
+		c.MemoryFree += other.MemoryFree
+	}
+}
+
+// coreCounts returns the core counts of the capacity
+func (c *Capacity) coreCounts() CoreCounts {
+	return CoreCounts{CoresTotal: c.CoresTotal, CoresFree: c.CoresFree, CoresUsed: c.CoresUsed, CoresReserved: c.CoresReserved}
+}
+
+// gpuCounts returns the GPU counts of the capacity
+func (c *Capacity) gpuCounts() GPUCounts {
+	return GPUCounts{GPUsTotal: c.GPUsTotal, GPUsFree: c.GPUsFree, GPUsUsed: c.GPUsUsed, GPUsReserved: c.GPUsReserved}
+}
+
+// MarkReservedCPUs sets the status of the free CPU Core nodes of the given logical CPUs to
+// "reserved", cores already used by a container keep their status
+func (g *FlexTopoGraph) MarkReservedCPUs(cpus []int) {
+	g.lock()
+	defer g.mu.Unlock()
+
+	for _, cpu := range cpus {
+		if node, exists := g.Nodes[fmt.Sprintf("core-%d", g.coreOf(cpu))]; exists && node.Status() == StatusFree {
+			node.Usage().Status = StatusReserved
+		}
+	}
+}
+
+// UpdateRollups sets the core, GPU and free memory counts on every CoreGroup, NUMANode
+// and Socket node. It must run after the usage passes so the counts match the leaves.
+func (g *FlexTopoGraph) UpdateRollups() {
+	g.lock()
+	defer g.mu.Unlock()
+
+	for _, socket := range g.index.byType["Socket"] {
+		socketCapacity := Capacity{MemoryFree: -1}
+		for _, numaNode := range socket.Children {
+			socketCapacity.add(g.numaCapacity(numaNode))
+		}
+		attributes := socket.Socket()
+		attributes.CoreCounts = socketCapacity.coreCounts()
+		attributes.GPUCounts = socketCapacity.gpuCounts()
+		attributes.MemoryFree = max(socketCapacity.MemoryFree, 0)
+	}
+}
+
+// numaCapacity sets the rollup attributes of a NUMA node and its core groups and returns
+// the NUMA node's capacity
+func (g *FlexTopoGraph) numaCapacity(numaNode *Node) Capacity {
+	numaCapacity := Capacity{MemoryFree: -1}
+	for _, coreGroup := range numaNode.Children {
+		groupCapacity := Capacity{MemoryFree: -1}
+		for _, core := range coreGroup.Children {
+			groupCapacity.countCore(core)
+		}
+		coreGroup.CoreGroup().CoreCounts = groupCapacity.coreCounts()
+		numaCapacity.add(groupCapacity)
+	}
+	for _, edge := range g.index.in[numaNode.ID]["attached-to"] {
+		if edge.Source.Type == "GPU" {
+			numaCapacity.countGPU(edge.Source)
+		}
+	}
+	numaCapacity.addMemory(numaNode)
+	attributes := numaNode.NUMANode()
+	attributes.CoreCounts = numaCapacity.coreCounts()
+	attributes.GPUCounts = numaCapacity.gpuCounts()
+	return numaCapacity
+}
+
+// Summary returns the capacity of the whole node, GPUs without a known NUMA node included
+func (g *FlexTopoGraph) Summary() Capacity {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.summary()
+}
+
+// summary computes the node capacity from the leaf nodes
+func (g *FlexTopoGraph) summary() Capacity {
+	capacity := Capacity{MemoryFree: -1}
+	for _, core := range g.index.byType["CPUCore"] {
+		capacity.countCore(core)
+	}
+	for _, gpu := range g.index.byType["GPU"] {
+		capacity.countGPU(gpu)
+	}
+	for _, numaNode := range g.index.byType["NUMANode"] {
+		capacity.addMemory(numaNode)
+	}
+	return capacity
+}
@@ -0,0 +1,48 @@
+package graph
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateRollups(t *testing.T) {
+	graph := newQueryTestGraph()
+	graph.SetNUMAMemory(0, 65536, 1024)
+	graph.SetNUMAMemory(1, 65536, 2048)
+	graph.UpdateGPUUsage(Consumer{Pod: "pod-a"}, []string{"GPU-b"})
+	graph.MarkReservedCPUs([]int{0, 1})
+	graph.UpdateRollups()
+
+	// core-0 is used by pod-a and keeps its status, core-1 is reserved
+	coreGroup := graph.Nodes["coregroup-0-0"].CoreGroup()
+	assert.Equal(t, CoreCounts{CoresTotal: 2, CoresUsed: 1, CoresReserved: 1}, coreGroup.CoreCounts)
+
+	numa := graph.Nodes["numa-1"].NUMANode()
+	assert.Equal(t, 4, numa.CoresTotal)
+	assert.Equal(t, 3, numa.CoresFree)
+	assert.Equal(t, GPUCounts{GPUsTotal: 1, GPUsUsed: 1}, numa.GPUCounts)
+
+	socket := graph.Nodes["socket-0"].Socket()
+	assert.Equal(t, 2, socket.CoresFree)
+	assert.Equal(t, 1, socket.GPUsFree)
+	assert.Equal(t, 1024, socket.MemoryFree)
+
+	summary := graph.Summary()
+	assert.Equal(t, Capacity{
+		CoresTotal: 8, CoresFree: 5, CoresUsed: 2, CoresReserved: 1,
+		GPUsTotal: 2, GPUsFree: 1, GPUsUsed: 1,
+		MemoryFree: 3072,
+	}, summary)
+
+	topology := graph.ToTopology()
+	assert.Equal(t, 5, topology.Summary.CoresFree)
+	for _, node := range topology.Nodes {
+		if node.ID == "numa-1" {
+			var attributes map[string]interface{}
+			assert.NoError(t, json.Unmarshal(node.Attributes.Raw, &attributes))
+			assert.Equal(t, 3.0, attributes["coresFree"])
+		}
+	}
+}
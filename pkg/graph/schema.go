@@ -0,0 +1,344 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Statuses of CPU Core, GPU and NIC nodes
+const (
+	StatusFree     = "free"
+	StatusUsed     = "used"
+	StatusReserved = "reserved"
+	StatusOffline  = "offline"
+)
+
+// AttributeKind is the value type of an attribute
+type AttributeKind string
+
+// Attribute kinds, the Go type of the typed attribute field is given for each
+const (
+	KindInt          AttributeKind = "int"          // int
+	KindFloat        AttributeKind = "float"        // float64
+	KindString       AttributeKind = "string"       // string
+	KindConsumers    AttributeKind = "consumers"    // []Consumer
+	KindGPUProcesses AttributeKind = "gpuProcesses" // []GPUProcess
+)
+
+// AttributeSchema describes one attribute of a node type
+type AttributeSchema struct {
+	Kind AttributeKind
+	// Unit of numeric attributes, e.g. "MiB" or "percent"
+	Unit string
+	// Enum lists the allowed values of string attributes, any value is allowed if empty
+	Enum []string
+	// Required attributes must be present on every node of the type
+	Required bool
+}
+
+// NodeSchema describes the attributes of a node type, keyed by attribute name
+type NodeSchema map[string]AttributeSchema
+
+// CoreCounts are the core rollups of aggregate nodes, set by UpdateRollups
+type CoreCounts struct {
+	CoresTotal    int `json:"coresTotal,omitempty"`
+	CoresFree     int `json:"coresFree,omitempty"`
+	CoresUsed     int `json:"coresUsed,omitempty"`
+	CoresReserved int `json:"coresReserved,omitempty"`
+}
+
+// GPUCounts are the GPU rollups of Socket and NUMANode nodes, set by UpdateRollups
+type GPUCounts struct {
+	GPUsTotal    int `json:"gpusTotal,omitempty"`
+	GPUsFree     int `json:"gpusFree,omitempty"`
+	GPUsUsed     int `json:"gpusUsed,omitempty"`
+	GPUsReserved int `json:"gpusReserved,omitempty"`
+}
+
+// Usage holds the allocation attributes of CPU Core, GPU and NIC nodes
+type Usage struct {
+	Status    string     `json:"status" enum:"free,used,reserved,offline"`
+	UsedBy    string     `json:"usedBy,omitempty"`
+	Consumers []Consumer `json:"consumers,omitempty"`
+}
+
+// SocketAttributes are the attributes of Socket nodes
+type SocketAttributes struct {
+	CoreCounts
+	GPUCounts
+	MemoryFree int `json:"memoryFree,omitempty" unit:"MiB"`
+}
+
+// NUMANodeAttributes are the attributes of NUMANode nodes
+type NUMANodeAttributes struct {
+	CoreCounts
+	GPUCounts
+	MemoryTotal int `json:"memoryTotal,omitempty" unit:"MiB"`
+	MemoryFree  int `json:"memoryFree,omitempty" unit:"MiB"`
+}
+
+// MemoryReported reports whether the memory of the NUMA node is known, see SetNUMAMemory
+func (a *NUMANodeAttributes) MemoryReported() bool {
+	return a.MemoryTotal > 0
+}
+
+// CoreGroupAttributes are the attributes of CoreGroup nodes
+type CoreGroupAttributes struct {
+	CoreCounts
+	// NodeID is the ID of the NUMA node the group belongs to
+	NodeID     int `json:"nodeID"`
+	GroupIndex int `json:"groupIndex"`
+}
+
+// CPUCoreAttributes are the attributes of CPUCore nodes
+type CPUCoreAttributes struct {
+	Usage
+	Utilization float64 `json:"utilization,omitempty" unit:"percent"`
+}
+
+// GPUAttributes are the attributes of GPU nodes
+type GPUAttributes struct {
+	Usage
+	UUID              string       `json:"uuid"`
+	Name              string       `json:"name"`
+	MemoryTotal       int          `json:"memoryTotal" unit:"MiB"`
+	PCIBusID          string       `json:"pciBusId,omitempty"`
+	PCIeSwitch        string       `json:"pcieSwitch,omitempty"`
+	SMUtilization     float64      `json:"smUtilization,omitempty" unit:"percent"`
+	MemoryUtilization float64      `json:"memoryUtilization,omitempty" unit:"percent"`
+	MemoryUsed        int          `json:"memoryUsed,omitempty" unit:"MiB"`
+	Processes         []GPUProcess `json:"processes,omitempty"`
+}
+
+// NICAttributes are the attributes of NIC nodes
+type NICAttributes struct {
+	Usage
+	Name       string `json:"name"`
+	PCIBusID   string `json:"pciBusId"`
+	PCIeSwitch string `json:"pcieSwitch,omitempty"`
+}
+
+// registeredType is a node type registered with RegisterSchema
+type registeredType struct {
+	schema NodeSchema
+	// structType is the typed attribute struct stored on nodes of the type
+	structType reflect.Type
+	// fields maps attribute names to the index of their struct field, names lists them in
+	// field order
+	fields map[string][]int
+	names  []string
+}
+
+// registry holds the registration of every node type
+var registry = map[string]*registeredType{}
+
+func init() {
+	RegisterSchema("Socket", SocketAttributes{})
+	RegisterSchema("NUMANode", NUMANodeAttributes{})
+	RegisterSchema("CoreGroup", CoreGroupAttributes{})
+	RegisterSchema("CPUCore", CPUCoreAttributes{})
+	RegisterSchema("GPU", GPUAttributes{})
+	RegisterSchema("NIC", NICAttributes{})
+}
+
+// RegisterSchema registers the attributes of a node type from a typed attribute struct, which
+// nodes of the type then store their attributes in. Attribute names come from the json tags,
+// fields without omitempty are required. The unit and enum tags set the unit and the allowed
+// values.
+func RegisterSchema(nodeType string, attributes interface{}) {
+	registered := &registeredType{
+		schema:     NodeSchema{},
+		structType: reflect.TypeOf(attributes),
+		fields:     make(map[string][]int),
+	}
+	registered.addFields(registered.structType, nil)
+	registry[nodeType] = registered
+}
+
+// LookupSchema returns the schema of a node type
+func LookupSchema(nodeType string) (NodeSchema, bool) {
+	registered, ok := registry[nodeType]
+	if !ok {
+		return nil, false
+	}
+	return registered.schema, true
+}
+
+// NodeTypes returns the node types with a registered schema, in name order
+func NodeTypes() []string {
+	nodeTypes := make([]string, 0, len(registry))
+	for nodeType := range registry {
+		nodeTypes = append(nodeTypes, nodeType)
+	}
+	sort.Strings(nodeTypes)
+	return nodeTypes
+}
+
+// addFields adds the fields of a struct type to the registration, flattening embedded structs.
+// index is the field index of the struct type within the attribute struct.
+func (r *registeredType) addFields(structType reflect.Type, index []int) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		fieldIndex := append(append([]int(nil), index...), i)
+		if field.Anonymous {
+			r.addFields(field.Type, fieldIndex)
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		attribute := AttributeSchema{
+			Kind:     kindOf(field.Type),
+			Unit:     field.Tag.Get("unit"),
+			Required: !strings.Contains(options, "omitempty"),
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			attribute.Enum = strings.Split(enum, ",")
+		}
+		r.schema[name] = attribute
+		r.fields[name] = fieldIndex
+		r.names = append(r.names, name)
+	}
+}
+
+// registration returns the registration of the node's type if its typed attributes are
+// stored, or nil
+func (n *Node) registration() *registeredType {
+	registered, ok := registry[n.Type]
+	if !ok || n.typed == nil || reflect.TypeOf(n.typed).Elem() != registered.structType {
+		return nil
+	}
+	return registered
+}
+
+// kindOf maps a field type of an attribute struct to its kind
+func kindOf(fieldType reflect.Type) AttributeKind {
+	switch fieldType {
+	case reflect.TypeOf([]Consumer{}):
+		return KindConsumers
+	case reflect.TypeOf([]GPUProcess{}):
+		return KindGPUProcesses
+	}
+	switch fieldType.Kind() {
+	case reflect.Int:
+		return KindInt
+	case reflect.Float64:
+		return KindFloat
+	case reflect.String:
+		return KindString
+	}
+	panic(fmt.Sprintf("graph: unsupported attribute type %s", fieldType))
+}
+
+// IsVendorAttribute reports whether an attribute name carries a vendor prefix, such as
+// "nvidia.com/migMode". Vendor attributes are not part of any schema.
+func IsVendorAttribute(name string) bool {
+	return strings.Contains(name, "/")
+}
+
+// ValidateNode checks the attributes of node against the schema of its type: required
+// attributes must be set and strings must have one of their allowed values. Node.Attributes
+// may only hold attributes outside the schema, which must carry a vendor prefix.
+func ValidateNode(node *Node) error {
+	registered, ok := registry[node.Type]
+	if !ok {
+		return fmt.Errorf("%s: unknown node type %q", node.ID, node.Type)
+	}
+
+	var errs []error
+	names := make([]string, 0, len(node.Attributes))
+	for name := range node.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if IsVendorAttribute(name) {
+			continue
+		}
+		attribute, known := registered.schema[name]
+		if !known {
+			errs = append(errs, fmt.Errorf("%s: unknown attribute %q, extra attributes need a vendor prefix", node.ID, name))
+		} else if err := attribute.check(node.Attributes[name]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: attribute %q %v", node.ID, name, err))
+		} else {
+			errs = append(errs, fmt.Errorf("%s: attribute %q is not stored in the typed attributes", node.ID, name))
+		}
+	}
+
+	var missing []string
+	for _, name := range registered.names {
+		attribute := registered.schema[name]
+		value, present := node.Attribute(name)
+		if attribute.Required && (!present || value == "") {
+			missing = append(missing, name)
+			continue
+		}
+		if present {
+			if err := attribute.check(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: attribute %q %v", node.ID, name, err))
+			}
+		}
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		errs = append(errs, fmt.Errorf("%s: missing required attribute %q", node.ID, name))
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateAttributes checks the attributes of every node against the schema of its type
+func (g *FlexTopoGraph) ValidateAttributes() error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	nodes := mapValues(g.Nodes)
+	SortNodes(nodes)
+	var errs []error
+	for _, node := range nodes {
+		if err := ValidateNode(node); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// check verifies that value has the kind of the attribute and one of its allowed values
+func (a AttributeSchema) check(value interface{}) error {
+	valid := false
+	switch a.Kind {
+	case KindInt:
+		switch v := value.(type) {
+		case int, int64:
+			valid = true
+		case float64:
+			// Decoded JSON numbers are float64
+			valid = v == math.Trunc(v)
+		}
+	case KindFloat:
+		switch value.(type) {
+		case float64, int:
+			valid = true
+		}
+	case KindString:
+		s, ok := value.(string)
+		valid = ok
+		if ok && len(a.Enum) > 0 {
+			for _, allowed := range a.Enum {
+				if s == allowed {
+					return nil
+				}
+			}
+			return fmt.Errorf("has value %q, allowed values are %s", s, strings.Join(a.Enum, ", "))
+		}
+	case KindConsumers:
+		_, valid = value.([]Consumer)
+	case KindGPUProcesses:
+		_, valid = value.([]GPUProcess)
+	}
+	if !valid {
+		return fmt.Errorf("has type %T, expected %s", value, a.Kind)
+	}
+	return nil
+}
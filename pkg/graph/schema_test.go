@@ -0,0 +1,79 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaRegistry(t *testing.T) {
+	schema, ok := LookupSchema("GPU")
+	assert.True(t, ok)
+	assert.Equal(t, AttributeSchema{Kind: KindInt, Unit: "MiB", Required: true}, schema["memoryTotal"])
+	assert.Equal(t, []string{StatusFree, StatusUsed, StatusReserved, StatusOffline}, schema["status"].Enum)
+	assert.Equal(t, KindConsumers, schema["consumers"].Kind)
+	assert.False(t, schema["consumers"].Required)
+
+	schema, ok = LookupSchema("NUMANode")
+	assert.True(t, ok)
+	assert.Equal(t, KindInt, schema["coresFree"].Kind, "Embedded rollup fields are flattened")
+
+	_, ok = LookupSchema("FPGA")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"CPUCore", "CoreGroup", "GPU", "NIC", "NUMANode", "Socket"}, NodeTypes())
+}
+
+func TestValidateAttributes(t *testing.T) {
+	graph := newQueryTestGraph()
+	graph.SetNUMAMemory(0, 65536, 1024)
+	graph.UpdateCoreUtilization(map[int]float64{1: 12.5})
+	graph.UpdateGPUUtilization("GPU-a", GPUUtilization{SMUtilization: 50, MemoryUsed: 1024, Processes: []GPUProcess{{PID: 1, UsedMemory: 1024}}})
+	graph.UpdateRollups()
+	assert.NoError(t, graph.ValidateAttributes())
+
+	// Vendor attributes are allowed, other extras are not
+	graph.Nodes["gpu-0"].Attributes["nvidia.com/migMode"] = "disabled"
+	assert.NoError(t, graph.ValidateAttributes())
+	graph.Nodes["gpu-0"].Attributes["migMode"] = "disabled"
+	graph.Nodes["gpu-0"].Attributes["status"] = StatusUsed
+	graph.Nodes["core-2"].CPUCore().Status = "busy"
+	graph.Nodes["gpu-1"].GPU().UUID = ""
+	err := graph.ValidateAttributes()
+	assert.EqualError(t, err, `core-2: attribute "status" has value "busy", allowed values are free, used, reserved, offline
+gpu-0: unknown attribute "migMode", extra attributes need a vendor prefix
+gpu-0: attribute "status" is not stored in the typed attributes
+gpu-1: missing required attribute "uuid"`)
+
+	assert.Error(t, ValidateNode(&Node{ID: "fpga-0", Type: "FPGA"}))
+}
+
+func TestTypedAttributes(t *testing.T) {
+	graph := newQueryTestGraph()
+	graph.UpdateGPUUsage(Consumer{Pod: "pod-a", Container: "main"}, []string{"GPU-b"})
+	node := graph.Nodes["gpu-1"]
+	assert.Equal(t, "GPU-b", node.GPU().UUID)
+	assert.Equal(t, 24564, node.GPU().MemoryTotal)
+	assert.Equal(t, StatusUsed, node.Status())
+	assert.Equal(t, []Consumer{{Pod: "pod-a", Container: "main"}}, node.GPU().Consumers)
+	assert.Nil(t, node.CPUCore())
+
+	// Values decoded from JSON are converted, attributes outside the schema stay in Attributes
+	assert.NoError(t, node.SetAttribute("memoryUsed", 512.0))
+	assert.NoError(t, node.SetAttribute("nvidia.com/migMode", "disabled"))
+	assert.EqualError(t, node.SetAttribute("memoryTotal", "24Gi"), `attribute "memoryTotal" has type string, expected int`)
+	assert.Equal(t, 512, node.GPU().MemoryUsed)
+	assert.Equal(t, map[string]interface{}{"nvidia.com/migMode": "disabled"}, node.Attributes)
+
+	// Optional attributes are absent while zero, like in the JSON form
+	_, present := node.Attribute("smUtilization")
+	assert.False(t, present)
+	attributes := node.AttributeMap()
+	assert.Equal(t, "GPU-b", attributes["uuid"])
+	assert.Equal(t, "disabled", attributes["nvidia.com/migMode"])
+	assert.NotContains(t, attributes, "smUtilization")
+
+	// Nodes built as struct literals get their schema attributes moved when added
+	graph.AddNode(&Node{ID: "nic-eth1", Type: "NIC", Attributes: map[string]interface{}{"name": "eth1", "status": StatusFree}})
+	assert.Equal(t, "eth1", graph.Nodes["nic-eth1"].NIC().Name)
+	assert.Empty(t, graph.Nodes["nic-eth1"].Attributes)
+}
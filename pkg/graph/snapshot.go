@@ -0,0 +1,125 @@
+package graph
+
+// Snapshot returns a deep copy of the graph that is frozen: its nodes, edges and attributes
+// never change afterwards, so it can be read concurrently without locking while the
+// original keeps being modified. Calling a mutating method on a snapshot panics.
+func (g *FlexTopoGraph) Snapshot() *FlexTopoGraph {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	snapshot := &FlexTopoGraph{
+		Nodes:          make(map[string]*Node, len(g.Nodes)),
+		Edges:          make(map[string]*Edge, len(g.Edges)),
+		CoreGroupSize:  g.CoreGroupSize,
+		unmatchedUsage: append([]unmatchedUsage(nil), g.unmatchedUsage...),
+		frozen:         true,
+	}
+
+	// Copy nodes first, then rewire the pointers between them
+	copies := make(map[*Node]*Node, len(g.Nodes))
+	for id, node := range g.Nodes {
+		nodeCopy := &Node{
+			ID:         node.ID,
+			Type:       node.Type,
+			Attributes: deepCopyAttributes(node.Attributes),
+			typed:      node.deepCopyTyped(),
+		}
+		copies[node] = nodeCopy
+		snapshot.Nodes[id] = nodeCopy
+	}
+	copyOf := func(node *Node) *Node {
+		if nodeCopy, ok := copies[node]; ok {
+			return nodeCopy
+		}
+		// Edges may reference nodes that are not part of Nodes
+		nodeCopy := &Node{ID: node.ID, Type: node.Type, Attributes: deepCopyAttributes(node.Attributes), typed: node.deepCopyTyped()}
+		copies[node] = nodeCopy
+		return nodeCopy
+	}
+	for node, nodeCopy := range copies {
+		if node.Children != nil {
+			nodeCopy.Children = make([]*Node, 0, len(node.Children))
+			for _, child := range node.Children {
+				nodeCopy.Children = append(nodeCopy.Children, copyOf(child))
+			}
+		}
+		if node.Parent != nil {
+			nodeCopy.Parent = copyOf(node.Parent)
+		}
+	}
+	for key, edge := range g.Edges {
+		snapshot.Edges[key] = &Edge{
+			Source: copyOf(edge.Source),
+			Target: copyOf(edge.Target),
+			Type:   edge.Type,
+			Weight: edge.Weight,
+		}
+	}
+
+	snapshot.rebuildIndex()
+	return snapshot
+}
+
+// deepCopyAttributes copies an attribute map including nested maps and slices
+func deepCopyAttributes(attributes map[string]interface{}) map[string]interface{} {
+	if attributes == nil {
+		return nil
+	}
+	attributesCopy := make(map[string]interface{}, len(attributes))
+	for key, value := range attributes {
+		attributesCopy[key] = deepCopyValue(value)
+	}
+	return attributesCopy
+}
+
+// deepCopyValue copies the attribute value types used in the graph. Scalars are
+// returned as is.
+func deepCopyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return deepCopyAttributes(v)
+	case []interface{}:
+		valuesCopy := make([]interface{}, len(v))
+		for i, item := range v {
+			valuesCopy[i] = deepCopyValue(item)
+		}
+		return valuesCopy
+	case []Consumer:
+		consumers := make([]Consumer, len(v))
+		for i, consumer := range v {
+			consumers[i] = consumer.DeepCopy()
+		}
+		return consumers
+	case []GPUProcess:
+		return append([]GPUProcess(nil), v...)
+	case []int:
+		return append([]int(nil), v...)
+	case []string:
+		return append([]string(nil), v...)
+	case map[int]int64:
+		mapCopy := make(map[int]int64, len(v))
+		for key, item := range v {
+			mapCopy[key] = item
+		}
+		return mapCopy
+	}
+	return value
+}
+
+// StableSnapshot returns a snapshot of the graph without VolatileAttributes and with
+// consumers reduced to their identity, see Consumer.Stable. Two stable snapshots differ
+// only by hardware and allocation changes.
+func (g *FlexTopoGraph) StableSnapshot() *FlexTopoGraph {
+	snapshot := g.Snapshot()
+	for _, node := range snapshot.Nodes {
+		for name := range VolatileAttributes {
+			node.DeleteAttribute(name)
+		}
+		if usage := node.Usage(); usage != nil {
+			for i, consumer := range usage.Consumers {
+				usage.Consumers[i] = consumer.Stable()
+			}
+		}
+	}
+	return snapshot
+}
@@ -0,0 +1,116 @@
+package graph
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	graph := newQueryTestGraph()
+	graph.UpdateCPUUsage(Consumer{Pod: "pod-b", MemsAllowed: []int{0}, NUMAMemory: map[int]int64{0: 1024}}, []int{1})
+	snapshot := graph.Snapshot()
+
+	// The snapshot is structurally identical
+	assert.Equal(t, len(graph.Nodes), len(snapshot.Nodes))
+	assert.Equal(t, len(graph.Edges), len(snapshot.Edges))
+	assert.Equal(t, graph.Nodes["core-1"].AttributeMap(), snapshot.Nodes["core-1"].AttributeMap())
+	assert.Equal(t, "coregroup-0-0", snapshot.Nodes["core-1"].Parent.ID)
+	assert.Same(t, snapshot.Nodes["coregroup-0-0"], snapshot.Nodes["core-1"].Parent, "Pointers must stay within the snapshot")
+	assert.Same(t, snapshot.Nodes["gpu-1"], snapshot.NodeByUUID("GPU-b"))
+	assert.Equal(t, ids(graph.SameNUMA(graph.Nodes["gpu-1"])), ids(snapshot.SameNUMA(snapshot.Nodes["gpu-1"])))
+
+	// Changes to the original do not leak into the snapshot
+	graph.UpdateCPUUsage(Consumer{Pod: "pod-c"}, []int{2})
+	consumers := graph.Nodes["core-1"].Usage().Consumers
+	consumers[0].NUMAMemory[0] = 4096
+	assert.Equal(t, "free", snapshot.Nodes["core-2"].Status())
+	assert.Equal(t, int64(1024), snapshot.Nodes["core-1"].Usage().Consumers[0].NUMAMemory[0])
+
+	// Snapshots are frozen
+	assert.Panics(t, func() { snapshot.UpdateCPUUsage(Consumer{Pod: "pod-d"}, []int{3}) })
+}
+
+func TestStableSnapshot(t *testing.T) {
+	graph := newQueryTestGraph()
+	consumer := Consumer{
+		Pod: "pod-b", Namespace: "default", Container: "main", MemsAllowed: []int{0},
+		NUMAMemory: map[int]int64{1: 4096}, CPUUsage: 0.5,
+	}
+	graph.UpdateCPUUsage(consumer, []int{1})
+	graph.UpdateCoreUtilization(map[int]float64{1: 50})
+	snapshot := graph.StableSnapshot()
+
+	// The identity of consumers remains, their usage and memory placement are dropped
+	assert.Equal(t, []Consumer{{Pod: "pod-b", Namespace: "default", Container: "main", MemsAllowed: []int{0}}},
+		snapshot.Nodes["core-1"].Usage().Consumers)
+	assert.Zero(t, snapshot.Nodes["core-1"].CPUCore().Utilization)
+	assert.Equal(t, "used", snapshot.Nodes["core-1"].Status())
+	// The original keeps its attributes
+	assert.Equal(t, 0.5, graph.Nodes["core-1"].Usage().Consumers[0].CPUUsage)
+	assert.Equal(t, 50.0, graph.Nodes["core-1"].CPUCore().Utilization)
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	graph := newLargeGraph(64, 8)
+	var wg sync.WaitGroup
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(2)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				graph.UpdateCPUUsage(Consumer{Pod: fmt.Sprintf("pod-%d", worker)}, []int{(worker*16 + i) % 64})
+				graph.UpdateGPUUsage(Consumer{Pod: fmt.Sprintf("pod-%d", worker)}, []string{fmt.Sprintf("GPU-%04d", i%8)})
+				graph.UpdateCoreUtilization(map[int]float64{i % 64: float64(i)})
+			}
+		}(worker)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				snapshot := graph.Snapshot()
+				snapshot.ToTopology()
+				snapshot.Select(OfType("CPUCore"), AttributeEquals("status", "used"))
+				graph.Query("samenuma(gpu-0) CPUCore[status=free]")
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 8, len(graph.Select(OfType("GPU"), AttributeEquals("status", "used"))))
+}
+
+func TestConcurrentDiffOppositeOrder(t *testing.T) {
+	a, b := newLargeGraph(16, 2), newLargeGraph(16, 2)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		for worker := 0; worker < 4; worker++ {
+			wg.Add(2)
+			go func(worker int) {
+				defer wg.Done()
+				for i := 0; i < 200; i++ {
+					if worker%2 == 0 {
+						Diff(a, b)
+					} else {
+						Diff(b, a)
+					}
+				}
+			}(worker)
+			go func(worker int) {
+				defer wg.Done()
+				for i := 0; i < 200; i++ {
+					[]*FlexTopoGraph{a, b}[(worker+i)%2].UpdateCoreUtilization(map[int]float64{i % 16: float64(i)})
+				}
+			}(worker)
+		}
+		wg.Wait()
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("diffs of the same graphs in opposite order deadlocked")
+	}
+}
@@ -0,0 +1,203 @@
+package graph
+
+import (
+	"encoding/json"
+	"flextopo/pkg/crd"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// TopologyOptions controls how a graph is rendered into a FlexTopoTopology
+type TopologyOptions struct {
+	// Nested emits the nodes of the "contains" hierarchy, Socket, NUMANode, CoreGroup and
+	// CPUCore, as children of their parent instead of at the top level. The "contains" edges
+	// are implied by the nesting and not listed.
+	Nested bool
+	// Compact folds CPUCore nodes into lists of core IDs of their CoreGroup, split by status,
+	// so that the size of the topology no longer grows with a node and attribute map per core.
+	// NUMANodes carry the core lists of their cores as well. FromTopology restores the cores.
+	Compact bool
+}
+
+// ToTopology converts FlexTopoGraph to FlexTopoTopology, with nodes and edges in natural ID order
+func (g *FlexTopoGraph) ToTopology() *crd.FlexTopoTopology {
+	return g.ToTopologyWithOptions(TopologyOptions{})
+}
+
+// ToTopologyWithOptions converts FlexTopoGraph to FlexTopoTopology. The output is deterministic:
+// nodes, children and edges are in natural ID order and attributes are sorted by name.
+func (g *FlexTopoGraph) ToTopologyWithOptions(options TopologyOptions) *crd.FlexTopoTopology {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	topology := &crd.FlexTopoTopology{
+		Nodes: []crd.FlexTopoNode{},
+		Edges: []crd.FlexTopoEdge{},
+	}
+
+	// Convert nodes, nested nodes are emitted by their parent
+	nodes := mapValues(g.Nodes)
+	SortNodes(nodes)
+	for _, node := range nodes {
+		if (options.Nested && g.isNested(node)) || (options.Compact && g.isCompacted(node)) {
+			continue
+		}
+		topology.Nodes = append(topology.Nodes, g.topologyNode(node, options))
+	}
+
+	// Convert edges
+	for _, key := range sortedEdgeKeys(g.Edges) {
+		edge := g.Edges[key]
+		if edge.Type == "contains" && edge.Target.Parent == edge.Source &&
+			((options.Nested && g.isNested(edge.Target)) || (options.Compact && g.isCompacted(edge.Target))) {
+			continue
+		}
+		topologyEdge := crd.FlexTopoEdge{
+			Source: edge.Source.ID,
+			Target: edge.Target.ID,
+			Type:   edge.Type,
+			Weight: edge.Weight,
+		}
+		topology.Edges = append(topology.Edges, topologyEdge)
+	}
+
+	summary := crd.FlexTopoSummary(g.summary())
+	topology.Summary = &summary
+
+	return topology
+}
+
+// isNested reports whether node is emitted as a child of its parent in the nested layout
+func (g *FlexTopoGraph) isNested(node *Node) bool {
+	return node.Parent != nil && g.Nodes[node.Parent.ID] == node.Parent
+}
+
+// topologyNode converts a node, and its children in the nested layout
+func (g *FlexTopoGraph) topologyNode(node *Node, options TopologyOptions) crd.FlexTopoNode {
+	nodeAttributes := node.AttributeMap()
+	if options.Compact {
+		nodeAttributes = g.compactAttributes(node)
+	}
+	// Attributes only hold JSON-compatible values, so marshaling cannot fail
+	attributes, _ := json.Marshal(nodeAttributes)
+	topologyNode := crd.FlexTopoNode{
+		ID:         node.ID,
+		Type:       node.Type,
+		Attributes: runtime.RawExtension{Raw: attributes},
+	}
+	if options.Nested {
+		children := append([]*Node(nil), node.Children...)
+		SortNodes(children)
+		for _, child := range children {
+			if child.Parent == node && !(options.Compact && g.isCompacted(child)) {
+				topologyChild := g.topologyNode(child, options)
+				topologyNode.Children = append(topologyNode.Children, &topologyChild)
+			}
+		}
+	}
+	return topologyNode
+}
+
+// FromTopology rebuilds a FlexTopoGraph from the status of a FlexTopo, so that readers of the CRD
+// can use the queries and the planner of the agent. The flat, nested and compact layouts are
+// all accepted. Attributes in the registered schemas are decoded into the typed attributes,
+// e.g. consumers back to []Consumer, a value of the wrong type is an error. CoreGroupSize is
+// not part of the topology and stays 0.
+func FromTopology(topology *crd.FlexTopoTopology) (*FlexTopoGraph, error) {
+	g := NewFlexTopoGraph(0)
+
+	edges := append([]crd.FlexTopoEdge(nil), topology.Edges...)
+	var addNodes func(topologyNodes []crd.FlexTopoNode, parentID string) error
+	addNodes = func(topologyNodes []crd.FlexTopoNode, parentID string) error {
+		for _, topologyNode := range topologyNodes {
+			if _, exists := g.Nodes[topologyNode.ID]; exists {
+				return fmt.Errorf("duplicate node %s", topologyNode.ID)
+			}
+			node := NewNode(topologyNode.ID, topologyNode.Type)
+			node.Children = []*Node{}
+			if err := decodeAttributes(node, topologyNode.Attributes.Raw); err != nil {
+				return fmt.Errorf("node %s: %v", topologyNode.ID, err)
+			}
+			g.addNode(node)
+			// Nesting implies a "contains" edge from the parent
+			if parentID != "" {
+				edges = append(edges, crd.FlexTopoEdge{Source: parentID, Target: topologyNode.ID, Type: "contains"})
+			}
+			children := make([]crd.FlexTopoNode, 0, len(topologyNode.Children))
+			for _, child := range topologyNode.Children {
+				if child != nil {
+					children = append(children, *child)
+				}
+			}
+			if err := addNodes(children, topologyNode.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := addNodes(topology.Nodes, ""); err != nil {
+		return nil, err
+	}
+
+	// Recreate the cores of the compact layout
+	nodes := mapValues(g.Nodes)
+	SortNodes(nodes)
+	for _, node := range nodes {
+		coreEdges, err := g.expandCores(node)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %v", node.ID, err)
+		}
+		edges = append(edges, coreEdges...)
+	}
+
+	// Add edges in natural order, so that Children are ordered independent of the input
+	sort.SliceStable(edges, func(i, j int) bool {
+		return NaturalLess(edgeKey(edges[i]), edgeKey(edges[j]))
+	})
+	for _, topologyEdge := range edges {
+		source, sourceExists := g.Nodes[topologyEdge.Source]
+		target, targetExists := g.Nodes[topologyEdge.Target]
+		if !sourceExists || !targetExists {
+			return nil, fmt.Errorf("edge %s references a missing node", edgeKey(topologyEdge))
+		}
+		// An edge listed explicitly and implied by nesting keeps its weight
+		if edge := g.addEdge(source, target, topologyEdge.Type); topologyEdge.Weight != 0 {
+			edge.Weight = topologyEdge.Weight
+		}
+	}
+
+	return g, nil
+}
+
+// edgeKey returns the key of a topology edge in the Edges map
+func edgeKey(edge crd.FlexTopoEdge) string {
+	return fmt.Sprintf("%s-%s-%s", edge.Source, edge.Target, edge.Type)
+}
+
+// decodeAttributes decodes the JSON attributes of a node and sets them on node, converting
+// the values of attributes in the schema of its type to their Go type
+func decodeAttributes(node *Node, raw []byte) error {
+	var attributes map[string]interface{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &attributes); err != nil {
+			return fmt.Errorf("invalid attributes: %v", err)
+		}
+	}
+	for name, value := range attributes {
+		if err := node.SetAttribute(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reencode converts a decoded JSON value into out
+func reencode(value interface{}, out interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,164 @@
+package graph
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"flextopo/pkg/crd"
+)
+
+// newRoundTripGraph builds a graph that uses every kind of attribute and edge
+func newRoundTripGraph() *FlexTopoGraph {
+	graph := newQueryTestGraph()
+	graph.SetNUMAMemory(0, 65536, 1024)
+	graph.SetNUMADistance(0, 1, 21)
+	graph.SetNUMADistance(1, 0, 21)
+	nic := graph.NewNICNode("ib0", "0000:c1:00.0")
+	graph.AddNode(nic)
+	graph.AttachToNUMA(nic, 1)
+	graph.Connect(graph.Nodes["gpu-1"], nic, "pcie-switch", 0)
+	graph.UpdateCPUUsage(Consumer{
+		Pod: "pod-b", Namespace: "default", Container: "main", Kind: ContainerKindRegular,
+		MemsAllowed: []int{0}, NUMAMemory: map[int]int64{0: 1024, 1: 4096}, CPUUsage: 0.5,
+	}, []int{1})
+	graph.UpdateGPUUsage(Consumer{Pod: "pod-b", Namespace: "default"}, []string{"GPU-b"})
+	graph.UpdateCoreUtilization(map[int]float64{1: 87.5})
+	graph.UpdateGPUUtilization("GPU-b", GPUUtilization{SMUtilization: 40, MemoryUsed: 2048, Processes: []GPUProcess{{PID: 7, UsedMemory: 2048}}})
+	graph.Nodes["gpu-0"].Attributes["nvidia.com/migMode"] = "disabled"
+	graph.UpdateRollups()
+	return graph
+}
+
+func TestFromTopologyRoundTrip(t *testing.T) {
+	original := newRoundTripGraph()
+	rebuilt, err := FromTopology(original.ToTopology())
+	assert.NoError(t, err)
+
+	assert.True(t, Diff(original, rebuilt).Empty())
+	for id, node := range original.Nodes {
+		rebuiltNode := rebuilt.Nodes[id]
+		assert.Equal(t, node.Type, rebuiltNode.Type)
+		assert.Equal(t, node.AttributeMap(), rebuiltNode.AttributeMap(), "attributes of %s", id)
+		assert.Equal(t, ids(node.Children), ids(rebuiltNode.Children), "children of %s", id)
+		if node.Parent != nil {
+			assert.Same(t, rebuilt.Nodes[node.Parent.ID], rebuiltNode.Parent)
+		}
+	}
+	for key, edge := range original.Edges {
+		assert.Equal(t, edge.Weight, rebuilt.Edges[key].Weight)
+	}
+
+	// Indexes and queries work on the rebuilt graph
+	assert.Same(t, rebuilt.Nodes["gpu-1"], rebuilt.NodeByUUID("GPU-b"))
+	assert.Same(t, rebuilt.Nodes["nic-ib0"], rebuilt.NodeByPCIAddress("0000:c1:00.0"))
+	want, err := original.Query("samenuma(gpu-1) CPUCore[status=free]")
+	assert.NoError(t, err)
+	got, err := rebuilt.Query("samenuma(gpu-1) CPUCore[status=free]")
+	assert.NoError(t, err)
+	assert.Equal(t, ids(want), ids(got))
+	assert.NoError(t, rebuilt.Validate())
+	assert.NoError(t, rebuilt.ValidateAttributes())
+
+	// The rebuilt graph renders the same topology
+	assert.ElementsMatch(t, original.ToTopology().Edges, rebuilt.ToTopology().Edges)
+}
+
+func TestFromTopologyErrors(t *testing.T) {
+	node := crd.FlexTopoNode{ID: "gpu-0", Type: "GPU", Attributes: runtime.RawExtension{Raw: []byte(`{"uuid":"GPU-a"}`)}}
+
+	_, err := FromTopology(&crd.FlexTopoTopology{Nodes: []crd.FlexTopoNode{node, node}})
+	assert.EqualError(t, err, "duplicate node gpu-0")
+
+	_, err = FromTopology(&crd.FlexTopoTopology{
+		Nodes: []crd.FlexTopoNode{node},
+		Edges: []crd.FlexTopoEdge{{Source: "gpu-0", Target: "numa-0", Type: "attached-to"}},
+	})
+	assert.EqualError(t, err, "edge gpu-0-numa-0-attached-to references a missing node")
+
+	node.Attributes.Raw = []byte(`{"consumers":"pod-a"}`)
+	_, err = FromTopology(&crd.FlexTopoTopology{Nodes: []crd.FlexTopoNode{node}})
+	assert.Error(t, err)
+
+	// Nodes without attributes get an empty map
+	graph, err := FromTopology(&crd.FlexTopoTopology{Nodes: []crd.FlexTopoNode{{ID: "socket-0", Type: "Socket"}}})
+	assert.NoError(t, err)
+	assert.NotNil(t, graph.Nodes["socket-0"].Attributes)
+}
+
+func TestToSpecDeterministic(t *testing.T) {
+	graph := newRoundTripGraph()
+	first := graph.ToTopology()
+	assert.Equal(t, "core-0", first.Nodes[0].ID)
+	assert.Equal(t, "core-2", first.Nodes[2].ID, "Nodes should be in natural order")
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, first, graph.ToTopology())
+		assert.Equal(t, graph.ToTopologyWithOptions(TopologyOptions{Nested: true}), graph.ToTopologyWithOptions(TopologyOptions{Nested: true}))
+	}
+}
+
+func TestToSpecNested(t *testing.T) {
+	graph := newRoundTripGraph()
+	topology := graph.ToTopologyWithOptions(TopologyOptions{Nested: true})
+
+	// Only the roots of the hierarchy are at the top level
+	var roots []string
+	for _, node := range topology.Nodes {
+		roots = append(roots, node.ID)
+	}
+	assert.Equal(t, []string{"gpu-0", "gpu-1", "nic-ib0", "socket-0", "socket-1"}, roots)
+	socket := topology.Nodes[3]
+	assert.Equal(t, "numa-0", socket.Children[0].ID)
+	assert.Equal(t, "coregroup-0-0", socket.Children[0].Children[0].ID)
+	assert.Equal(t, []string{"core-0", "core-1"}, []string{socket.Children[0].Children[0].Children[0].ID, socket.Children[0].Children[0].Children[1].ID})
+	for _, edge := range topology.Edges {
+		assert.NotEqual(t, "contains", edge.Type, "contains edges are implied by the nesting")
+	}
+
+	// Nested topologies rebuild the same graph
+	rebuilt, err := FromTopology(topology)
+	assert.NoError(t, err)
+	assert.True(t, Diff(graph, rebuilt).Empty())
+	assert.Equal(t, graph.ToTopology(), rebuilt.ToTopology())
+}
+
+func TestToSpecCompact(t *testing.T) {
+	graph := newRoundTripGraph()
+	topology := graph.ToTopologyWithOptions(TopologyOptions{Compact: true})
+
+	// Cores are folded into the core lists of their core group and NUMA node
+	nodes := make(map[string]crd.FlexTopoNode)
+	for _, node := range topology.Nodes {
+		assert.NotEqual(t, "CPUCore", node.Type)
+		nodes[node.ID] = node
+	}
+	var attributes map[string]interface{}
+	assert.NoError(t, json.Unmarshal(nodes["coregroup-0-0"].Attributes.Raw, &attributes))
+	assert.Equal(t, map[string]interface{}{"used": "0-1"}, attributes[CoresAttribute])
+	assert.Equal(t, map[string]interface{}{"1": 87.5}, attributes[CoreUtilizationAttribute])
+	assert.Len(t, attributes[CoreSetsAttribute], 2, "cores used by different pods")
+	assert.NoError(t, json.Unmarshal(nodes["numa-0"].Attributes.Raw, &attributes))
+	assert.Equal(t, map[string]interface{}{"free": "2-3", "used": "0-1"}, attributes[CoresAttribute])
+	for _, edge := range topology.Edges {
+		assert.NotEqual(t, "CPUCore", graph.Nodes[edge.Target].Type, "contains edges to cores are implied")
+	}
+
+	// Compact topologies rebuild the same graph, also combined with the nested layout
+	for _, options := range []TopologyOptions{{Compact: true}, {Compact: true, Nested: true}} {
+		rebuilt, err := FromTopology(graph.ToTopologyWithOptions(options))
+		assert.NoError(t, err)
+		assert.True(t, Diff(graph, rebuilt).Empty())
+		for id, node := range graph.Nodes {
+			assert.Equal(t, node.AttributeMap(), rebuilt.Nodes[id].AttributeMap(), "attributes of %s", id)
+		}
+		assert.Equal(t, graph.ToTopology(), rebuilt.ToTopology())
+		assert.Equal(t, graph.ToTopologyWithOptions(options), rebuilt.ToTopologyWithOptions(options))
+	}
+
+	_, err := FromTopology(&crd.FlexTopoTopology{Nodes: []crd.FlexTopoNode{
+		{ID: "coregroup-0-0", Type: "CoreGroup", Attributes: runtime.RawExtension{Raw: []byte(`{"cores":{"free":"0-1"},"coreUtilization":{"2":10}}`)}},
+	}})
+	assert.EqualError(t, err, "node coregroup-0-0: coreUtilization references core 2, which is not in the core lists")
+}
@@ -0,0 +1,60 @@
+package graph
+
+import "fmt"
+
+// GPUProcess is a compute process running on a GPU
+type GPUProcess struct {
+	PID int `json:"pid"`
+	// UsedMemory is the GPU memory used by the process in MiB
+	UsedMemory int `json:"usedMemory"`
+}
+
+// GPUUtilization holds the live load of a GPU as reported by nvidia-smi
+type GPUUtilization struct {
+	// SMUtilization is the percentage of time a kernel was running on the GPU
+	SMUtilization float64
+	// MemoryUtilization is the percentage of time device memory was read or written
+	MemoryUtilization float64
+	// MemoryUsed is the allocated device memory in MiB
+	MemoryUsed int
+	Processes  []GPUProcess
+}
+
+// UpdateCoreUtilization sets the busy percentage of CPU Core nodes, keyed by logical CPU
+// number. The utilization of a core is the average of its SMT siblings.
+func (g *FlexTopoGraph) UpdateCoreUtilization(utilization map[int]float64) {
+	g.lock()
+	defer g.mu.Unlock()
+
+	total := make(map[int]float64)
+	cpus := make(map[int]int)
+	for cpu, busy := range utilization {
+		coreID := g.coreOf(cpu)
+		total[coreID] += busy
+		cpus[coreID]++
+	}
+	for coreID, busy := range total {
+		nodeID := fmt.Sprintf("core-%d", coreID)
+		if node, exists := g.Nodes[nodeID]; exists {
+			node.CPUCore().Utilization = busy / float64(cpus[coreID])
+		}
+	}
+}
+
+// UpdateGPUUtilization sets the live utilization of the GPU node with the given UUID
+func (g *FlexTopoGraph) UpdateGPUUtilization(uuid string, utilization GPUUtilization) {
+	g.lock()
+	defer g.mu.Unlock()
+
+	node := g.gpuByUUID(uuid)
+	if node == nil {
+		return
+	}
+	gpu := node.GPU()
+	gpu.SMUtilization = utilization.SMUtilization
+	gpu.MemoryUtilization = utilization.MemoryUtilization
+	gpu.MemoryUsed = utilization.MemoryUsed
+	if len(utilization.Processes) > 0 {
+		gpu.Processes = utilization.Processes
+	}
+}
@@ -0,0 +1,20 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flextopo/pkg/utils"
+)
+
+func TestUpdateCoreUtilizationSMT(t *testing.T) {
+	// CPUs 0 and 2 are the SMT siblings of core 0, CPU 1 is alone on core 1
+	graph := NewFlexTopoGraph(2)
+	graph.BuildCPUNodes([]utils.CPUInfo{{CPUID: 0, CoreID: 0}, {CPUID: 1, CoreID: 1}, {CPUID: 2, CoreID: 0}})
+	graph.UpdateCoreUtilization(map[int]float64{0: 80, 1: 30, 2: 20})
+
+	assert.Equal(t, 50.0, graph.Nodes["core-0"].CPUCore().Utilization)
+	assert.Equal(t, 30.0, graph.Nodes["core-1"].CPUCore().Utilization)
+	assert.NotContains(t, graph.Nodes, "core-2")
+}
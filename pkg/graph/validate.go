@@ -0,0 +1,129 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+)
+
+// unmatchedUsage is a core or GPU that a pod uses but that is not part of the graph
+type unmatchedUsage struct {
+	pod string
+	// resource is the core node ID or the GPU UUID
+	resource string
+}
+
+// rollupKeys are the attributes set by UpdateRollups, in Capacity field order
+var rollupKeys = []string{
+	"coresTotal", "coresFree", "coresUsed", "coresReserved",
+	"gpusTotal", "gpusFree", "gpusUsed", "gpusReserved",
+}
+
+// Validate checks the structural invariants of the graph: every core belongs to a core
+// group, edges only connect nodes of the graph, GPU UUIDs are unique, "contains" edges form
+// no cycle, all reported usage matched a node, and rollups add up. It returns all
+// violations joined into one error, or nil.
+func (g *FlexTopoGraph) Validate() error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var errs []error
+	nodes := mapValues(g.Nodes)
+	SortNodes(nodes)
+
+	uuids := make(map[string]string)
+	for _, node := range nodes {
+		if node.Type == "CPUCore" && (node.Parent == nil || node.Parent.Type != "CoreGroup") {
+			errs = append(errs, fmt.Errorf("%s: core does not belong to a core group", node.ID))
+		}
+		if uuid, _ := deviceIDs(node); uuid != "" {
+			if other, duplicate := uuids[uuid]; duplicate {
+				errs = append(errs, fmt.Errorf("%s: UUID %s is also used by %s", node.ID, uuid, other))
+			} else {
+				uuids[uuid] = node.ID
+			}
+		}
+	}
+
+	for _, key := range sortedEdgeKeys(g.Edges) {
+		edge := g.Edges[key]
+		for _, end := range []*Node{edge.Source, edge.Target} {
+			if g.Nodes[end.ID] != end {
+				errs = append(errs, fmt.Errorf("edge %s: node %s is not part of the graph", key, end.ID))
+			}
+		}
+	}
+
+	errs = append(errs, g.containsCycles(nodes)...)
+
+	for _, usage := range g.unmatchedUsage {
+		errs = append(errs, fmt.Errorf("pod %s uses %s, which is not part of the graph", usage.pod, usage.resource))
+	}
+
+	errs = append(errs, g.rollupErrors(nodes)...)
+	return errors.Join(errs...)
+}
+
+// containsCycles reports the nodes at which a cycle of "contains" edges is detected
+func (g *FlexTopoGraph) containsCycles(nodes []*Node) []error {
+	const (
+		unvisited = iota
+		inProgress
+		finished
+	)
+	var errs []error
+	state := make(map[string]int)
+	var visit func(node *Node)
+	visit = func(node *Node) {
+		state[node.ID] = inProgress
+		for _, edge := range g.index.out[node.ID]["contains"] {
+			switch state[edge.Target.ID] {
+			case unvisited:
+				visit(edge.Target)
+			case inProgress:
+				errs = append(errs, fmt.Errorf("%s: \"contains\" edge to %s closes a cycle", node.ID, edge.Target.ID))
+			}
+		}
+		state[node.ID] = finished
+	}
+	for _, node := range nodes {
+		if state[node.ID] == unvisited {
+			visit(node)
+		}
+	}
+	return errs
+}
+
+// rollupErrors recomputes the rollups of aggregate nodes that carry them and reports
+// those that do not match the leaves
+func (g *FlexTopoGraph) rollupErrors(nodes []*Node) []error {
+	var errs []error
+	for _, node := range nodes {
+		// Every aggregate node contains a core, so no core count means no rollups yet
+		coreCounts := node.coreCounts()
+		if coreCounts == nil || *coreCounts == (CoreCounts{}) {
+			continue
+		}
+		// GPUs are below their NUMA node through "attached-to" edges
+		capacity := Capacity{MemoryFree: -1}
+		for _, descendant := range g.descendants(node) {
+			switch descendant.Type {
+			case "CPUCore":
+				capacity.countCore(descendant)
+			case "GPU":
+				capacity.countGPU(descendant)
+			}
+		}
+		got := []int{coreCounts.CoresTotal, coreCounts.CoresFree, coreCounts.CoresUsed, coreCounts.CoresReserved}
+		want := []int{capacity.CoresTotal, capacity.CoresFree, capacity.CoresUsed, capacity.CoresReserved}
+		if gpuCounts := node.gpuCounts(); gpuCounts != nil {
+			got = append(got, gpuCounts.GPUsTotal, gpuCounts.GPUsFree, gpuCounts.GPUsUsed, gpuCounts.GPUsReserved)
+			want = append(want, capacity.GPUsTotal, capacity.GPUsFree, capacity.GPUsUsed, capacity.GPUsReserved)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				errs = append(errs, fmt.Errorf("%s: %s is %d, but the leaves add up to %d", node.ID, rollupKeys[i], got[i], want[i]))
+			}
+		}
+	}
+	return errs
+}
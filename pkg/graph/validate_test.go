@@ -0,0 +1,40 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	graph := newQueryTestGraph()
+	graph.UpdateGPUUsage(Consumer{Pod: "pod-a"}, []string{"GPU-a"})
+	graph.UpdateRollups()
+	assert.NoError(t, graph.Validate())
+	assert.NoError(t, graph.Snapshot().Validate())
+
+	// Usage of unknown cores and GPUs
+	graph.UpdateCPUUsage(Consumer{Pod: "pod-b"}, []int{42})
+	graph.UpdateGPUUsage(Consumer{Pod: "pod-b"}, []string{"GPU-z"})
+	// A core outside the hierarchy, a duplicate UUID and a dangling edge
+	graph.AddNode(&Node{ID: "core-99", Type: "CPUCore", Attributes: map[string]interface{}{"status": StatusFree}})
+	graph.Nodes["gpu-1"].GPU().UUID = "GPU-a"
+	graph.Connect(graph.Nodes["gpu-0"], &Node{ID: "nic-eth9", Type: "NIC"}, "pcie-switch", 0)
+	// A cycle and a stale rollup
+	graph.Connect(graph.Nodes["core-0"], graph.Nodes["socket-0"], "contains", 0)
+	graph.Nodes["numa-1"].NUMANode().CoresFree = 4
+
+	err := graph.Validate()
+	assert.Error(t, err)
+	for _, want := range []string{
+		"core-99: core does not belong to a core group",
+		"gpu-1: UUID GPU-a is also used by gpu-0",
+		"edge gpu-0-nic-eth9-pcie-switch: node nic-eth9 is not part of the graph",
+		"closes a cycle",
+		"pod pod-b uses core-42, which is not part of the graph",
+		"pod pod-b uses GPU-z, which is not part of the graph",
+		"numa-1: coresFree is 4, but the leaves add up to 3",
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+}
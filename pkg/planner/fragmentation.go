@@ -0,0 +1,399 @@
+package planner
+
+import (
+	"sort"
+	"strings"
+
+	"flextopo/pkg/graph"
+)
+
+// NUMAFragmentation describes how the free cores of one NUMA node are spread
+type NUMAFragmentation struct {
+	NUMANode  string
+	FreeCores int
+	// LargestFreeBlock is the longest run of free cores with consecutive IDs
+	LargestFreeBlock int
+	// LargestFreeGroup is the largest number of free cores in a single core group
+	LargestFreeGroup int
+	FreeGPUs         int
+	// Score is 0 if all free cores form one block and approaches 1 as they scatter
+	Score float64
+}
+
+// GPUPair is two free GPUs behind the same PCIe switch
+type GPUPair struct {
+	GPUs       [2]string
+	PCIeSwitch string
+}
+
+// Fragmentation summarizes how scattered the free resources of a node are
+type Fragmentation struct {
+	NUMANodes []NUMAFragmentation
+	FreeCores int
+	// LargestFreeBlock is the largest block of free consecutive cores within one NUMA node,
+	// i.e. the largest core request a single-NUMA placement can still get without splitting
+	LargestFreeBlock int
+	FreeGPUs         int
+	// FreeGPUPairs are disjoint pairs of free GPUs sharing a PCIe switch
+	FreeGPUPairs []GPUPair
+	// Score is 0 if the free cores form one block and approaches 1 as they scatter
+	Score float64
+}
+
+// Eviction is a set of pods whose eviction or migration would enlarge the largest aligned
+// free block or free another pair of GPUs sharing a PCIe switch
+type Eviction struct {
+	// Pods are namespace/name, or the name only if the namespace is unknown, in name order
+	Pods []string
+	// Cores and GPUs are everything the pods free, including resources outside the target
+	Cores []string
+	GPUs  []string
+	// NUMANode is where the largest free block is after the eviction
+	NUMANode string
+	// LargestFreeBlock is the largest block of free consecutive cores within one NUMA node
+	// after the eviction
+	LargestFreeBlock int
+	// Gain is the growth of the largest free block
+	Gain int
+	// GPUPairGain is the number of additional free GPU pairs sharing a PCIe switch
+	GPUPairGain int
+}
+
+// AnalyzeFragmentation computes the fragmentation of the free cores and GPUs of g
+func AnalyzeFragmentation(g *graph.FlexTopoGraph) Fragmentation {
+	return analyze(g, isFree)
+}
+
+// SuggestEvictions returns sets of pods whose eviction would produce a larger aligned free
+// block or another free GPU pair, best first. Every target, a block of consecutive cores in
+// one NUMA node or a GPU pair behind one PCIe switch, gets the smallest set of pods found
+// to free it: blocks are exact, GPU pairs are filled greedily by the pods holding the most
+// GPUs behind the switch. At most limit sets are returned, all of them if limit is not
+// positive. Only cores and GPUs used by the pod alone are considered freed.
+func SuggestEvictions(g *graph.FlexTopoGraph, limit int) []Eviction {
+	current := AnalyzeFragmentation(g)
+
+	podCores := make(map[string][]*graph.Node)
+	podGPUs := make(map[string][]*graph.Node)
+	for _, core := range g.NodesByType("CPUCore") {
+		if pod := exclusivePod(core); pod != "" {
+			podCores[pod] = append(podCores[pod], core)
+		}
+	}
+	for _, gpu := range g.NodesByType("GPU") {
+		if pod := exclusivePod(gpu); pod != "" {
+			podGPUs[pod] = append(podGPUs[pod], gpu)
+		}
+	}
+
+	var candidates [][]string
+	for _, numaNode := range g.NodesByType("NUMANode") {
+		candidates = append(candidates, blockEvictions(numaNode, current.LargestFreeBlock)...)
+	}
+	candidates = append(candidates, gpuPairEvictions(g)...)
+
+	var evictions []Eviction
+	seen := make(map[string]bool)
+	for _, pods := range candidates {
+		sort.Strings(pods)
+		key := strings.Join(pods, ",")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		freed := make(map[*graph.Node]bool)
+		var cores, gpus []*graph.Node
+		for _, pod := range pods {
+			cores = append(cores, podCores[pod]...)
+			gpus = append(gpus, podGPUs[pod]...)
+		}
+		for _, node := range cores {
+			freed[node] = true
+		}
+		for _, node := range gpus {
+			freed[node] = true
+		}
+		after := analyze(g, func(node *graph.Node) bool {
+			return freed[node] || isFree(node)
+		})
+		eviction := Eviction{
+			Pods:             pods,
+			Cores:            nodeIDs(sortedNodes(cores)),
+			GPUs:             nodeIDs(sortedNodes(gpus)),
+			LargestFreeBlock: after.LargestFreeBlock,
+			Gain:             after.LargestFreeBlock - current.LargestFreeBlock,
+			GPUPairGain:      len(after.FreeGPUPairs) - len(current.FreeGPUPairs),
+		}
+		for _, numa := range after.NUMANodes {
+			if numa.LargestFreeBlock == after.LargestFreeBlock {
+				eviction.NUMANode = numa.NUMANode
+				break
+			}
+		}
+		if eviction.Gain > 0 || eviction.GPUPairGain > 0 {
+			evictions = append(evictions, eviction)
+		}
+	}
+
+	// Prefer the largest gain, then the fewest pods, then the least disruptive eviction
+	sort.Slice(evictions, func(i, j int) bool {
+		a, b := evictions[i], evictions[j]
+		if a.Gain != b.Gain {
+			return a.Gain > b.Gain
+		}
+		if a.GPUPairGain != b.GPUPairGain {
+			return a.GPUPairGain > b.GPUPairGain
+		}
+		if len(a.Pods) != len(b.Pods) {
+			return len(a.Pods) < len(b.Pods)
+		}
+		if len(a.Cores)+len(a.GPUs) != len(b.Cores)+len(b.GPUs) {
+			return len(a.Cores)+len(a.GPUs) < len(b.Cores)+len(b.GPUs)
+		}
+		return strings.Join(a.Pods, ",") < strings.Join(b.Pods, ",")
+	})
+	if limit > 0 && len(evictions) > limit {
+		evictions = evictions[:limit]
+	}
+	return evictions
+}
+
+// blockEvictions returns, for every block size of numaNode larger than largest, the
+// smallest set of pods whose eviction frees a block of that size. A set is dropped if the
+// same pods also free the next larger block.
+func blockEvictions(numaNode *graph.Node, largest int) [][]string {
+	var cores []*graph.Node
+	for _, coreGroup := range numaNode.Children {
+		cores = append(cores, coreGroup.Children...)
+	}
+	cores = sortedNodes(cores)
+
+	var result [][]string
+	for size := largest + 1; size <= len(cores); size++ {
+		var best map[string]bool
+		for start := 0; start+size <= len(cores); start++ {
+			pods, ok := windowPods(cores[start : start+size])
+			if ok && (best == nil || len(pods) < len(best)) {
+				best = pods
+			}
+		}
+		if best == nil {
+			// A block this large needs a core no eviction frees, larger ones do too
+			break
+		}
+		set := make([]string, 0, len(best))
+		for pod := range best {
+			set = append(set, pod)
+		}
+		if n := len(result); n > 0 && len(result[n-1]) == len(set) && containsAll(best, result[n-1]) {
+			result[n-1] = set
+		} else {
+			result = append(result, set)
+		}
+	}
+	return result
+}
+
+// windowPods returns the pods to evict to free every core of a window of cores sorted by
+// ID, false if the cores are not consecutive or one of them cannot be freed
+func windowPods(cores []*graph.Node) (map[string]bool, bool) {
+	pods := make(map[string]bool)
+	for i, core := range cores {
+		if i > 0 && nodeIndex(core.ID) != nodeIndex(cores[i-1].ID)+1 {
+			return nil, false
+		}
+		if isFree(core) {
+			continue
+		}
+		pod := exclusivePod(core)
+		if pod == "" {
+			return nil, false
+		}
+		pods[pod] = true
+	}
+	return pods, true
+}
+
+// gpuPairEvictions returns, for every PCIe switch, a set of pods whose eviction frees one
+// more pair of GPUs behind it. Pods holding the most GPUs behind the switch are taken first.
+func gpuPairEvictions(g *graph.FlexTopoGraph) [][]string {
+	bySwitch := make(map[string][]*graph.Node)
+	for _, gpu := range g.NodesByType("GPU") {
+		if pcieSwitch := gpu.GPU().PCIeSwitch; pcieSwitch != "" {
+			bySwitch[pcieSwitch] = append(bySwitch[pcieSwitch], gpu)
+		}
+	}
+
+	var result [][]string
+	for _, gpus := range bySwitch {
+		free := 0
+		held := make(map[string]int)
+		for _, gpu := range gpus {
+			if isFree(gpu) {
+				free++
+			} else if pod := exclusivePod(gpu); pod != "" {
+				held[pod]++
+			}
+		}
+		// An odd free GPU needs one partner, otherwise a whole pair is needed
+		needed := 2 - free%2
+		pods := make([]string, 0, len(held))
+		for pod := range held {
+			pods = append(pods, pod)
+		}
+		sort.Slice(pods, func(i, j int) bool {
+			if held[pods[i]] != held[pods[j]] {
+				return held[pods[i]] > held[pods[j]]
+			}
+			return pods[i] < pods[j]
+		})
+		var set []string
+		for _, pod := range pods {
+			if needed <= 0 {
+				break
+			}
+			set = append(set, pod)
+			needed -= held[pod]
+		}
+		if needed <= 0 {
+			result = append(result, set)
+		}
+	}
+	return result
+}
+
+// containsAll reports whether set contains every pod
+func containsAll(set map[string]bool, pods []string) bool {
+	for _, pod := range pods {
+		if !set[pod] {
+			return false
+		}
+	}
+	return true
+}
+
+// analyze computes the fragmentation of g, treating the nodes accepted by free as free
+func analyze(g *graph.FlexTopoGraph, free func(*graph.Node) bool) Fragmentation {
+	result := Fragmentation{}
+	for _, numaNode := range g.NodesByType("NUMANode") {
+		numa := NUMAFragmentation{NUMANode: numaNode.ID}
+		var cores []*graph.Node
+		for _, coreGroup := range numaNode.Children {
+			groupFree := 0
+			for _, core := range coreGroup.Children {
+				cores = append(cores, core)
+				if free(core) {
+					groupFree++
+				}
+			}
+			if groupFree > numa.LargestFreeGroup {
+				numa.LargestFreeGroup = groupFree
+			}
+		}
+
+		// Walk the cores in ID order and measure runs of free consecutive cores
+		run, previous := 0, -2
+		for _, core := range sortedNodes(cores) {
+			index := nodeIndex(core.ID)
+			if !free(core) {
+				run = 0
+			} else {
+				if index == previous+1 {
+					run++
+				} else {
+					run = 1
+				}
+				numa.FreeCores++
+			}
+			if run > numa.LargestFreeBlock {
+				numa.LargestFreeBlock = run
+			}
+			previous = index
+		}
+		for _, edge := range g.InEdges(numaNode, "attached-to") {
+			if edge.Source.Type == "GPU" && free(edge.Source) {
+				numa.FreeGPUs++
+			}
+		}
+		numa.Score = fragmentationScore(numa.LargestFreeBlock, numa.FreeCores)
+
+		result.NUMANodes = append(result.NUMANodes, numa)
+		result.FreeCores += numa.FreeCores
+		if numa.LargestFreeBlock > result.LargestFreeBlock {
+			result.LargestFreeBlock = numa.LargestFreeBlock
+		}
+	}
+
+	// Pair up free GPUs behind the same PCIe switch
+	bySwitch := make(map[string][]*graph.Node)
+	var switches []string
+	for _, gpu := range g.NodesByType("GPU") {
+		if !free(gpu) {
+			continue
+		}
+		result.FreeGPUs++
+		pcieSwitch := gpu.GPU().PCIeSwitch
+		if pcieSwitch == "" {
+			continue
+		}
+		if bySwitch[pcieSwitch] == nil {
+			switches = append(switches, pcieSwitch)
+		}
+		bySwitch[pcieSwitch] = append(bySwitch[pcieSwitch], gpu)
+	}
+	sort.Strings(switches)
+	for _, pcieSwitch := range switches {
+		gpus := bySwitch[pcieSwitch]
+		for i := 0; i+1 < len(gpus); i += 2 {
+			result.FreeGPUPairs = append(result.FreeGPUPairs, GPUPair{
+				GPUs:       [2]string{gpus[i].ID, gpus[i+1].ID},
+				PCIeSwitch: pcieSwitch,
+			})
+		}
+	}
+
+	result.Score = fragmentationScore(result.LargestFreeBlock, result.FreeCores)
+	return result
+}
+
+// fragmentationScore is the share of free cores outside the largest free block
+func fragmentationScore(largestBlock, free int) float64 {
+	if free == 0 {
+		return 0
+	}
+	return 1 - float64(largestBlock)/float64(free)
+}
+
+// isFree reports whether a core or device is free
+func isFree(node *graph.Node) bool {
+	return node.Status() == graph.StatusFree
+}
+
+// exclusivePod returns the pod using node, or "" if the node is not used or shared by
+// several pods
+func exclusivePod(node *graph.Node) string {
+	usage := node.Usage()
+	if usage == nil || usage.Status != graph.StatusUsed {
+		return ""
+	}
+	consumers := usage.Consumers
+	if len(consumers) == 0 {
+		return usage.UsedBy
+	}
+	pod := podKey(consumers[0])
+	for _, consumer := range consumers[1:] {
+		if podKey(consumer) != pod {
+			return ""
+		}
+	}
+	return pod
+}
+
+// podKey identifies the pod of a consumer
+func podKey(consumer graph.Consumer) string {
+	if consumer.Namespace == "" {
+		return consumer.Pod
+	}
+	return consumer.Namespace + "/" + consumer.Pod
+}
@@ -0,0 +1,99 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flextopo/pkg/graph"
+)
+
+// newFragmentedGraph uses the cores of the test graph so that no NUMA node has more than
+// 4 consecutive free cores, and puts gpu-0 and gpu-1 behind one PCIe switch
+func newFragmentedGraph() *graph.FlexTopoGraph {
+	g := newTestGraph()
+	g.Nodes["gpu-0"].GPU().PCIeSwitch = "0000:01:00.0"
+	g.Nodes["gpu-1"].GPU().PCIeSwitch = "0000:01:00.0"
+	g.UpdateCPUUsage(graph.Consumer{Namespace: "default", Pod: "pod-a"}, []int{0, 1, 2, 3, 4, 5})
+	g.UpdateCPUUsage(graph.Consumer{Namespace: "default", Pod: "pod-b"}, []int{12})
+	g.UpdateCPUUsage(graph.Consumer{Namespace: "default", Pod: "pod-c"}, []int{16, 17, 18, 19, 21, 22, 23})
+	g.UpdateCPUUsage(graph.Consumer{Namespace: "default", Pod: "pod-d"}, []int{27})
+	g.UpdateCPUUsage(graph.Consumer{Namespace: "default", Pod: "pod-e"}, []int{24, 25})
+	g.UpdateGPUUsage(graph.Consumer{Namespace: "default", Pod: "pod-f"}, []string{"GPU-b"})
+	return g
+}
+
+func TestAnalyzeFragmentation(t *testing.T) {
+	fragmentation := AnalyzeFragmentation(newFragmentedGraph())
+
+	assert.Equal(t, 15, fragmentation.FreeCores)
+	assert.Equal(t, 4, fragmentation.LargestFreeBlock)
+	assert.InDelta(t, 1-4.0/15, fragmentation.Score, 1e-9)
+	assert.Equal(t, 4, fragmentation.FreeGPUs)
+	assert.Empty(t, fragmentation.FreeGPUPairs)
+
+	numa1 := fragmentation.NUMANodes[1]
+	assert.Equal(t, "numa-1", numa1.NUMANode)
+	assert.Equal(t, 7, numa1.FreeCores)
+	assert.Equal(t, 4, numa1.LargestFreeBlock)
+	assert.Equal(t, 4, numa1.LargestFreeGroup)
+	assert.Equal(t, 2, numa1.FreeGPUs)
+
+	numa2 := fragmentation.NUMANodes[2]
+	assert.Equal(t, 1, numa2.LargestFreeBlock)
+	assert.Equal(t, 0.0, numa2.Score)
+
+	// A machine without usage is not fragmented
+	idle := AnalyzeFragmentation(newTestGraph())
+	assert.Equal(t, 8, idle.LargestFreeBlock)
+	assert.InDelta(t, 0.75, idle.Score, 1e-9)
+}
+
+func TestSuggestEvictions(t *testing.T) {
+	evictions := SuggestEvictions(newFragmentedGraph(), 0)
+
+	var pods [][]string
+	for _, eviction := range evictions {
+		pods = append(pods, eviction.Pods)
+	}
+	// pod-e alone does not enlarge any block, the least disruptive evictions come first
+	assert.Equal(t, [][]string{
+		{"default/pod-b"}, {"default/pod-a"}, {"default/pod-c"}, {"default/pod-d", "default/pod-e"},
+		{"default/pod-d"}, {"default/pod-f"},
+	}, pods)
+
+	best := evictions[0]
+	assert.Equal(t, []string{"core-12"}, best.Cores)
+	assert.Equal(t, "numa-1", best.NUMANode)
+	assert.Equal(t, 8, best.LargestFreeBlock)
+	assert.Equal(t, 4, best.Gain)
+
+	// No single pod frees all of numa-3, pod-d and pod-e together do
+	both := evictions[3]
+	assert.Equal(t, []string{"core-24", "core-25", "core-27"}, both.Cores)
+	assert.Equal(t, "numa-3", both.NUMANode)
+	assert.Equal(t, 8, both.LargestFreeBlock)
+	assert.Equal(t, 2, evictions[4].Gain)
+
+	gpuEviction := evictions[5]
+	assert.Equal(t, []string{"gpu-1"}, gpuEviction.GPUs)
+	assert.Equal(t, 0, gpuEviction.Gain)
+	assert.Equal(t, 1, gpuEviction.GPUPairGain)
+
+	assert.Equal(t, 2, len(SuggestEvictions(newFragmentedGraph(), 2)))
+}
+
+func TestSuggestEvictionsGPUPair(t *testing.T) {
+	// The free pair behind the switch needs both pods holding its GPUs
+	g := newTestGraph()
+	g.Nodes["gpu-0"].GPU().PCIeSwitch = "0000:01:00.0"
+	g.Nodes["gpu-1"].GPU().PCIeSwitch = "0000:01:00.0"
+	g.UpdateGPUUsage(graph.Consumer{Pod: "pod-a"}, []string{"GPU-a"})
+	g.UpdateGPUUsage(graph.Consumer{Pod: "pod-b"}, []string{"GPU-b"})
+
+	evictions := SuggestEvictions(g, 0)
+	assert.Equal(t, 1, len(evictions))
+	assert.Equal(t, []string{"pod-a", "pod-b"}, evictions[0].Pods)
+	assert.Equal(t, []string{"gpu-0", "gpu-1"}, evictions[0].GPUs)
+	assert.Equal(t, 1, evictions[0].GPUPairGain)
+}
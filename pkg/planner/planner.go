@@ -0,0 +1,466 @@
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"flextopo/pkg/graph"
+)
+
+// Alignment policies a Request can ask for
+const (
+	// PolicySingleNUMA requires all devices to be local to one NUMA node
+	PolicySingleNUMA = "single-numa"
+	// PolicySingleSocket requires all devices to be local to one socket
+	PolicySingleSocket = "single-socket"
+	// PolicyBestEffort spans as few NUMA nodes as possible, across sockets if needed
+	PolicyBestEffort = "best-effort"
+)
+
+// Score penalties applied to a placement
+const (
+	penaltyPerExtraNUMA   = 20.0
+	penaltyPerExtraSocket = 30.0
+	penaltyPerSplitGroup  = 5.0
+	penaltyUnattachedGPU  = 10.0
+)
+
+// Request describes the resources a workload needs
+type Request struct {
+	GPUs  int
+	Cores int
+	// MemoryMiB is the free memory needed on the selected NUMA nodes
+	MemoryMiB int
+	NICs      int
+	// Policy is one of PolicySingleNUMA, PolicySingleSocket or PolicyBestEffort
+	Policy string
+}
+
+// Placement is a candidate assignment of devices for a Request
+type Placement struct {
+	// NUMANodes are the IDs of the NUMA nodes the placement spans
+	NUMANodes []string
+	Cores     []string
+	GPUs      []string
+	NICs      []string
+	// Score ranks placements from 0 to 100, higher is better
+	Score float64
+	// Explanation describes how the placement was built and scored
+	Explanation string
+}
+
+// numaDomain collects the free resources local to one NUMA node
+type numaDomain struct {
+	node   *graph.Node
+	index  int
+	socket string
+	// coreGroups holds the free cores of every core group, ordered by core group ID
+	coreGroups [][]*graph.Node
+	gpus       []*graph.Node
+	nics       []*graph.Node
+	// memoryFree is -1 if the NUMA node does not report its memory
+	memoryFree int
+}
+
+// freeCores returns the number of free cores in the domain
+func (d *numaDomain) freeCores() int {
+	count := 0
+	for _, group := range d.coreGroups {
+		count += len(group)
+	}
+	return count
+}
+
+// Planner computes topology-aware placements on a FlexTopoGraph
+type Planner struct {
+	graph   *graph.FlexTopoGraph
+	domains []*numaDomain
+	// unattachedGPUs are free GPUs without a known NUMA node
+	unattachedGPUs []*graph.Node
+}
+
+// NewPlanner creates a Planner for the free resources of g
+func NewPlanner(g *graph.FlexTopoGraph) *Planner {
+	p := &Planner{graph: g}
+	attachedGPUs := make(map[string]bool)
+
+	for _, node := range g.NodesByType("NUMANode") {
+		domain := &numaDomain{node: node, index: nodeIndex(node.ID), memoryFree: -1}
+		if node.Parent != nil {
+			domain.socket = node.Parent.ID
+		}
+		if numa := node.NUMANode(); numa.MemoryReported() {
+			domain.memoryFree = numa.MemoryFree
+		}
+		for _, coreGroup := range sortedNodes(node.Children) {
+			var free []*graph.Node
+			for _, core := range sortedNodes(coreGroup.Children) {
+				if core.Status() == graph.StatusFree {
+					free = append(free, core)
+				}
+			}
+			if len(free) > 0 {
+				domain.coreGroups = append(domain.coreGroups, free)
+			}
+		}
+		for _, edge := range g.InEdges(node, "attached-to") {
+			if edge.Source.Status() != graph.StatusFree {
+				continue
+			}
+			switch edge.Source.Type {
+			case "GPU":
+				domain.gpus = append(domain.gpus, edge.Source)
+				attachedGPUs[edge.Source.ID] = true
+			case "NIC":
+				domain.nics = append(domain.nics, edge.Source)
+			}
+		}
+		p.domains = append(p.domains, domain)
+	}
+
+	for _, node := range g.NodesByType("GPU") {
+		if node.Status() == graph.StatusFree && !attachedGPUs[node.ID] {
+			p.unattachedGPUs = append(p.unattachedGPUs, node)
+		}
+	}
+
+	sort.Slice(p.domains, func(i, j int) bool {
+		return p.domains[i].index < p.domains[j].index
+	})
+	for _, domain := range p.domains {
+		domain.gpus = sortedNodes(domain.gpus)
+		domain.nics = sortedNodes(domain.nics)
+	}
+	return p
+}
+
+// Plan returns the candidate placements for req, best first. An empty result means the
+// request cannot be satisfied under its policy.
+func (p *Planner) Plan(req Request) ([]Placement, error) {
+	if req.GPUs < 0 || req.Cores < 0 || req.MemoryMiB < 0 || req.NICs < 0 {
+		return nil, fmt.Errorf("invalid request: negative resource count")
+	}
+	switch req.Policy {
+	case PolicySingleNUMA, PolicySingleSocket, PolicyBestEffort:
+	default:
+		return nil, fmt.Errorf("unsupported alignment policy: %s", req.Policy)
+	}
+
+	var placements []Placement
+	seen := make(map[string]bool)
+	for _, seed := range p.domains {
+		domains := p.expand(seed, req)
+		if domains == nil {
+			continue
+		}
+		key := domainKey(domains)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if placement, ok := p.place(domains, req); ok {
+			placements = append(placements, placement)
+		}
+	}
+
+	sort.SliceStable(placements, func(i, j int) bool {
+		if placements[i].Score != placements[j].Score {
+			return placements[i].Score > placements[j].Score
+		}
+		return strings.Join(placements[i].NUMANodes, ",") < strings.Join(placements[j].NUMANodes, ",")
+	})
+	return placements, nil
+}
+
+// expand grows a set of NUMA domains from seed until it can hold req. Every step adds the
+// domain that covers most of the unmet part of req, preferring domains of the same socket,
+// and domains that add nothing to it are skipped. It returns nil if the policy does not
+// allow a large enough set.
+func (p *Planner) expand(seed *numaDomain, req Request) []*numaDomain {
+	domains := []*numaDomain{seed}
+	if p.fits(domains, req) {
+		return domains
+	}
+	if req.Policy == PolicySingleNUMA {
+		return nil
+	}
+
+	var candidates []*numaDomain
+	for _, domain := range p.domains {
+		if domain == seed {
+			continue
+		}
+		// A domain of unknown memory can never be part of a set that fits a memory request
+		if domain.memoryFree < 0 && req.MemoryMiB > 0 {
+			continue
+		}
+		if domain.socket == seed.socket || req.Policy == PolicyBestEffort {
+			candidates = append(candidates, domain)
+		}
+	}
+	for !p.fits(domains, req) {
+		missing := p.shortfall(domains, req)
+		best, bestCoverage := -1, 0.0
+		for i, domain := range candidates {
+			coverage := domain.coverage(missing)
+			if coverage == 0 {
+				continue
+			}
+			if best < 0 || betterCandidate(domain, coverage, candidates[best], bestCoverage, seed.socket) {
+				best, bestCoverage = i, coverage
+			}
+		}
+		if best < 0 {
+			return nil
+		}
+		domains = append(domains, candidates[best])
+		candidates = append(candidates[:best], candidates[best+1:]...)
+	}
+	return domains
+}
+
+// betterCandidate reports whether domain a should be added before domain b when growing a
+// set from a seed on socket: domains of the same socket first, then the one covering more of
+// the unmet request, then the one with more free cores
+func betterCandidate(a *numaDomain, coverageA float64, b *numaDomain, coverageB float64, socket string) bool {
+	if (a.socket == socket) != (b.socket == socket) {
+		return a.socket == socket
+	}
+	if coverageA != coverageB {
+		return coverageA > coverageB
+	}
+	return a.freeCores() > b.freeCores()
+}
+
+// shortfall returns the part of req that a set of domains cannot hold
+func (p *Planner) shortfall(domains []*numaDomain, req Request) Request {
+	missing := req
+	for _, domain := range domains {
+		missing.Cores -= domain.freeCores()
+		missing.GPUs -= len(domain.gpus)
+		missing.NICs -= len(domain.nics)
+		if domain.memoryFree > 0 {
+			missing.MemoryMiB -= domain.memoryFree
+		}
+	}
+	if req.Policy == PolicyBestEffort {
+		missing.GPUs -= len(p.unattachedGPUs)
+	}
+	missing.Cores = max(missing.Cores, 0)
+	missing.GPUs = max(missing.GPUs, 0)
+	missing.NICs = max(missing.NICs, 0)
+	missing.MemoryMiB = max(missing.MemoryMiB, 0)
+	return missing
+}
+
+// coverage returns how much of missing the domain holds, as the sum of the covered fraction
+// of every resource. It is 0 if the domain adds nothing to missing.
+func (d *numaDomain) coverage(missing Request) float64 {
+	covered := 0.0
+	add := func(free, needed int) {
+		if needed > 0 && free > 0 {
+			covered += float64(min(free, needed)) / float64(needed)
+		}
+	}
+	add(d.freeCores(), missing.Cores)
+	add(len(d.gpus), missing.GPUs)
+	add(len(d.nics), missing.NICs)
+	add(d.memoryFree, missing.MemoryMiB)
+	return covered
+}
+
+// fits reports whether a set of domains holds enough free resources for req
+func (p *Planner) fits(domains []*numaDomain, req Request) bool {
+	cores, gpus, nics, memory := 0, 0, 0, 0
+	for _, domain := range domains {
+		cores += domain.freeCores()
+		gpus += len(domain.gpus)
+		nics += len(domain.nics)
+		if domain.memoryFree < 0 && req.MemoryMiB > 0 {
+			return false
+		}
+		memory += domain.memoryFree
+	}
+	// GPUs without a known NUMA node can only be used when alignment is not required
+	if req.Policy == PolicyBestEffort {
+		gpus += len(p.unattachedGPUs)
+	}
+	return cores >= req.Cores && gpus >= req.GPUs && nics >= req.NICs && memory >= req.MemoryMiB
+}
+
+// place picks devices from a set of domains that fits req and scores the result
+func (p *Planner) place(domains []*numaDomain, req Request) (Placement, bool) {
+	placement := Placement{}
+	sockets := make(map[string]bool)
+	memory := 0
+	for _, domain := range domains {
+		placement.NUMANodes = append(placement.NUMANodes, domain.node.ID)
+		sockets[domain.socket] = true
+		memory += domain.memoryFree
+	}
+
+	cores, splitGroups := pickCores(domains, req.Cores)
+	placement.Cores = nodeIDs(cores)
+
+	var gpus, nics []*graph.Node
+	for _, domain := range domains {
+		gpus = append(gpus, domain.gpus...)
+		nics = append(nics, domain.nics...)
+	}
+	localGPUs := len(gpus)
+	if len(gpus) < req.GPUs {
+		gpus = append(gpus, p.unattachedGPUs...)
+	}
+	if len(gpus) < req.GPUs || len(nics) < req.NICs || len(cores) < req.Cores {
+		return Placement{}, false
+	}
+	placement.GPUs = nodeIDs(gpus[:req.GPUs])
+	placement.NICs = nodeIDs(nics[:req.NICs])
+	unattached := 0
+	if req.GPUs > localGPUs {
+		unattached = req.GPUs - localGPUs
+	}
+
+	score := 100.0
+	score -= penaltyPerExtraNUMA * float64(len(domains)-1)
+	score -= penaltyPerExtraSocket * float64(len(sockets)-1)
+	score -= penaltyPerSplitGroup * float64(splitGroups)
+	score -= penaltyUnattachedGPU * float64(unattached)
+	if score < 0 {
+		score = 0
+	}
+	placement.Score = score
+
+	var explanation []string
+	if len(domains) == 1 {
+		explanation = append(explanation, fmt.Sprintf("single NUMA %s on %s", domains[0].node.ID, domains[0].socket))
+	} else {
+		explanation = append(explanation, fmt.Sprintf("%d NUMA nodes (%s) on %d sockets",
+			len(domains), strings.Join(placement.NUMANodes, ", "), len(sockets)))
+	}
+	if req.Cores > 0 {
+		explanation = append(explanation, fmt.Sprintf("%d cores, %d partially used core groups", req.Cores, splitGroups))
+	}
+	if req.GPUs > 0 {
+		explanation = append(explanation, fmt.Sprintf("%d GPUs, %d without NUMA locality", req.GPUs, unattached))
+	}
+	if req.NICs > 0 {
+		explanation = append(explanation, fmt.Sprintf("%d local NICs", req.NICs))
+	}
+	if req.MemoryMiB > 0 {
+		explanation = append(explanation, fmt.Sprintf("%d MiB free memory for %d MiB requested", memory, req.MemoryMiB))
+	}
+	selectedGPUs := gpus[:req.GPUs]
+	if path, ok := p.worstPath(selectedGPUs, selectedGPUs); ok {
+		explanation = append(explanation, "farthest GPU pair "+path.String())
+	}
+	if path, ok := p.worstPath(selectedGPUs, nics[:req.NICs]); ok {
+		explanation = append(explanation, "farthest GPU-NIC pair "+path.String())
+	}
+	placement.Explanation = strings.Join(explanation, "; ")
+
+	return placement, true
+}
+
+// worstPath returns the most expensive of the paths between distinct nodes of from and to
+func (p *Planner) worstPath(from, to []*graph.Node) (graph.Path, bool) {
+	var worst graph.Path
+	found := false
+	for _, a := range from {
+		for _, b := range to {
+			if a == b {
+				continue
+			}
+			path, err := p.graph.Distance(a, b)
+			if err != nil {
+				continue
+			}
+			if !found || path.Cost > worst.Cost {
+				worst, found = path, true
+			}
+		}
+	}
+	return worst, found
+}
+
+// pickCores takes count free cores from the domains in order. Within a domain it prefers the
+// smallest core group that holds all remaining cores, otherwise it drains the largest group.
+// It returns the cores and the number of core groups left partially used.
+func pickCores(domains []*numaDomain, count int) ([]*graph.Node, int) {
+	var cores []*graph.Node
+	splitGroups := 0
+	for _, domain := range domains {
+		groups := make([][]*graph.Node, len(domain.coreGroups))
+		copy(groups, domain.coreGroups)
+		for count > len(cores) && len(groups) > 0 {
+			remaining := count - len(cores)
+			best := -1
+			for i, group := range groups {
+				if len(group) >= remaining && (best < 0 || len(group) < len(groups[best])) {
+					best = i
+				}
+			}
+			if best < 0 {
+				// No group holds all remaining cores, drain the largest one
+				best = 0
+				for i, group := range groups {
+					if len(group) > len(groups[best]) {
+						best = i
+					}
+				}
+			}
+			take := groups[best]
+			if len(take) > remaining {
+				take = take[:remaining]
+				splitGroups++
+			}
+			cores = append(cores, take...)
+			groups = append(groups[:best], groups[best+1:]...)
+		}
+	}
+	return cores, splitGroups
+}
+
+// nodeIndex extracts the trailing number of IDs such as "numa-3" or "core-12"
+func nodeIndex(id string) int {
+	index, err := strconv.Atoi(id[strings.LastIndex(id, "-")+1:])
+	if err != nil {
+		return -1
+	}
+	return index
+}
+
+// sortedNodes returns nodes ordered by their trailing index, then by ID
+func sortedNodes(nodes []*graph.Node) []*graph.Node {
+	sorted := make([]*graph.Node, len(nodes))
+	copy(sorted, nodes)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := nodeIndex(sorted[i].ID), nodeIndex(sorted[j].ID)
+		if a != b {
+			return a < b
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
+
+// nodeIDs returns the IDs of nodes
+func nodeIDs(nodes []*graph.Node) []string {
+	ids := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.ID)
+	}
+	return ids
+}
+
+// domainKey identifies a set of domains independent of their order
+func domainKey(domains []*numaDomain) string {
+	ids := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		ids = append(ids, domain.node.ID)
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
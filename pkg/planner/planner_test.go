@@ -0,0 +1,195 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flextopo/pkg/graph"
+	"flextopo/pkg/utils"
+)
+
+// newTestGraph builds a synthetic machine with 2 sockets, 2 NUMA nodes per socket and
+// 8 cores per NUMA node in core groups of 4. NUMA 0 and 1 have 2 GPUs each, NUMA 2 has
+// 1 GPU and a NIC, NUMA 3 has none. Every NUMA node has 64 GiB free memory.
+func newTestGraph() *graph.FlexTopoGraph {
+	g := graph.NewFlexTopoGraph(4)
+	var cpuInfos []utils.CPUInfo
+	for cpu := 0; cpu < 32; cpu++ {
+		cpuInfos = append(cpuInfos, utils.CPUInfo{CPUID: cpu, CoreID: cpu, SocketID: cpu / 16, NumaNodeID: cpu / 8})
+	}
+	g.BuildCPUNodes(cpuInfos)
+	for numa := 0; numa < 4; numa++ {
+		g.SetNUMAMemory(numa, 65536, 65536)
+	}
+	gpuNUMA := []int{0, 0, 1, 1, 2}
+	for index, numa := range gpuNUMA {
+		gpu := g.NewGPUNode(index, "GPU-"+string(rune('a'+index)), "NVIDIA GeForce RTX 4090", 24564)
+		g.AddNode(gpu)
+		g.AttachToNUMA(gpu, numa)
+	}
+	nic := g.NewNICNode("ib0", "0000:c1:00.0")
+	g.AddNode(nic)
+	g.AttachToNUMA(nic, 2)
+	return g
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name          string
+		prepare       func(g *graph.FlexTopoGraph)
+		request       Request
+		wantCount     int
+		wantNUMANodes []string
+		wantCores     []string
+		wantGPUs      []string
+		wantNICs      []string
+		wantScore     float64
+	}{
+		{
+			name:          "Single NUMA Prefers Lowest Index",
+			request:       Request{GPUs: 2, Cores: 4, Policy: PolicySingleNUMA},
+			wantCount:     2,
+			wantNUMANodes: []string{"numa-0"},
+			wantCores:     []string{"core-0", "core-1", "core-2", "core-3"},
+			wantGPUs:      []string{"gpu-0", "gpu-1"},
+			wantScore:     100,
+		},
+		{
+			name: "Single NUMA Avoids Busy Node",
+			prepare: func(g *graph.FlexTopoGraph) {
+				g.UpdateCPUUsage(graph.Consumer{Pod: "busy"}, []int{0, 1, 2, 3, 4, 5})
+			},
+			request:       Request{GPUs: 2, Cores: 4, Policy: PolicySingleNUMA},
+			wantCount:     1,
+			wantNUMANodes: []string{"numa-1"},
+			wantCores:     []string{"core-8", "core-9", "core-10", "core-11"},
+			wantGPUs:      []string{"gpu-2", "gpu-3"},
+			wantScore:     100,
+		},
+		{
+			name: "Best Fit Core Group",
+			prepare: func(g *graph.FlexTopoGraph) {
+				g.UpdateCPUUsage(graph.Consumer{Pod: "busy"}, []int{0, 1})
+			},
+			request:       Request{Cores: 2, Policy: PolicySingleNUMA},
+			wantCount:     4,
+			wantNUMANodes: []string{"numa-0"},
+			wantCores:     []string{"core-2", "core-3"},
+			wantScore:     100,
+		},
+		{
+			name:          "Single NUMA Impossible",
+			request:       Request{GPUs: 3, Policy: PolicySingleNUMA},
+			wantCount:     0,
+			wantNUMANodes: nil,
+		},
+		{
+			name:          "Single Socket Spans Two NUMA Nodes",
+			request:       Request{GPUs: 3, Cores: 12, Policy: PolicySingleSocket},
+			wantCount:     1,
+			wantNUMANodes: []string{"numa-0", "numa-1"},
+			wantCores:     []string{"core-0", "core-1", "core-2", "core-3", "core-4", "core-5", "core-6", "core-7", "core-8", "core-9", "core-10", "core-11"},
+			wantGPUs:      []string{"gpu-0", "gpu-1", "gpu-2"},
+			wantScore:     80,
+		},
+		{
+			name:          "Best Effort Crosses Sockets",
+			request:       Request{GPUs: 5, Policy: PolicyBestEffort},
+			wantCount:     2,
+			wantNUMANodes: []string{"numa-0", "numa-1", "numa-2"},
+			wantGPUs:      []string{"gpu-0", "gpu-1", "gpu-2", "gpu-3", "gpu-4"},
+			wantScore:     30,
+		},
+		{
+			name: "Best Effort Skips Empty NUMA Node",
+			prepare: func(g *graph.FlexTopoGraph) {
+				// numa-1 sits between numa-0 and numa-2 but has nothing left to offer
+				g.UpdateCPUUsage(graph.Consumer{Pod: "busy"}, []int{8, 9, 10, 11, 12, 13, 14, 15})
+				g.UpdateGPUUsage(graph.Consumer{Pod: "busy"}, []string{"GPU-c", "GPU-d"})
+			},
+			request:       Request{GPUs: 3, Cores: 4, Policy: PolicyBestEffort},
+			wantCount:     3,
+			wantNUMANodes: []string{"numa-0", "numa-2"},
+			wantCores:     []string{"core-0", "core-1", "core-2", "core-3"},
+			wantGPUs:      []string{"gpu-0", "gpu-1", "gpu-4"},
+			wantScore:     50,
+		},
+		{
+			name:          "NIC Locality",
+			request:       Request{GPUs: 1, NICs: 1, Cores: 2, Policy: PolicySingleNUMA},
+			wantCount:     1,
+			wantNUMANodes: []string{"numa-2"},
+			wantCores:     []string{"core-16", "core-17"},
+			wantGPUs:      []string{"gpu-4"},
+			wantNICs:      []string{"nic-ib0"},
+			wantScore:     95,
+		},
+		{
+			name:          "Memory Spans NUMA Nodes",
+			request:       Request{MemoryMiB: 100000, Policy: PolicySingleSocket},
+			wantCount:     2,
+			wantNUMANodes: []string{"numa-0", "numa-1"},
+			wantScore:     80,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGraph()
+			if tt.prepare != nil {
+				tt.prepare(g)
+			}
+			placements, err := NewPlanner(g).Plan(tt.request)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCount, len(placements))
+			if tt.wantCount == 0 {
+				return
+			}
+			best := placements[0]
+			assert.Equal(t, tt.wantNUMANodes, best.NUMANodes)
+			if tt.wantCores != nil {
+				assert.Equal(t, tt.wantCores, best.Cores)
+			}
+			if tt.wantGPUs != nil {
+				assert.Equal(t, tt.wantGPUs, best.GPUs)
+			}
+			if tt.wantNICs != nil {
+				assert.Equal(t, tt.wantNICs, best.NICs)
+			}
+			assert.Equal(t, tt.wantScore, best.Score)
+			assert.NotEmpty(t, best.Explanation)
+		})
+	}
+}
+
+func TestPlanDeterministic(t *testing.T) {
+	request := Request{GPUs: 1, Cores: 3, Policy: PolicyBestEffort}
+	first, err := NewPlanner(newTestGraph()).Plan(request)
+	assert.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		again, err := NewPlanner(newTestGraph()).Plan(request)
+		assert.NoError(t, err)
+		assert.Equal(t, first, again)
+	}
+}
+
+func TestPlanInvalidRequest(t *testing.T) {
+	planner := NewPlanner(newTestGraph())
+	_, err := planner.Plan(Request{Cores: 1, Policy: "pack"})
+	assert.Error(t, err)
+	_, err = planner.Plan(Request{Cores: -1, Policy: PolicyBestEffort})
+	assert.Error(t, err)
+}
+
+func TestPlanExplainsDistance(t *testing.T) {
+	g := newTestGraph()
+	placements, err := NewPlanner(g).Plan(Request{GPUs: 1, NICs: 1, Policy: PolicySingleNUMA})
+	assert.NoError(t, err)
+	assert.Contains(t, placements[0].Explanation, "farthest GPU-NIC pair gpu-4 -attached-to- numa-2 -attached-to- nic-ib0 (cost 2.0)")
+
+	g.Connect(g.Nodes["gpu-0"], g.Nodes["gpu-1"], "pcie-switch", 0)
+	placements, err = NewPlanner(g).Plan(Request{GPUs: 2, Policy: PolicySingleNUMA})
+	assert.NoError(t, err)
+	assert.Contains(t, placements[0].Explanation, "farthest GPU pair gpu-0 -pcie-switch- gpu-1 (cost 0.5)")
+}
@@ -0,0 +1,81 @@
+package reporter
+
+import (
+	"context"
+	"errors"
+	"flextopo/pkg/utils"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+)
+
+// Cleaner deletes the FlexTopos of removed nodes. It lists every FlexTopo and Node of the
+// cluster, so a single instance runs it, not the agent on every node.
+type Cleaner struct {
+	// metadataClient lists objects without their spec and status
+	metadataClient metadata.Interface
+	logger         utils.Logger
+}
+
+// NewCleaner creates a Cleaner with the in-cluster configuration
+func NewCleaner(logger utils.Logger) (*Cleaner, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	metadataClient, err := metadata.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return newCleaner(metadataClient, logger), nil
+}
+
+// newCleaner creates a Cleaner that works through metadataClient
+func newCleaner(metadataClient metadata.Interface, logger utils.Logger) *Cleaner {
+	return &Cleaner{metadataClient: metadataClient, logger: logger}
+}
+
+// CleanupOrphans deletes the FlexTopos whose Node no longer exists and returns how many were
+// deleted. FlexTopos are garbage collected with their Node, this handles those that were
+// created before they had an owner.
+func (c *Cleaner) CleanupOrphans() (int, error) {
+	// FlexTopos are listed first, so that every listed FlexTopo was created before the Nodes
+	// are listed and its Node is in the list if it still exists
+	flextopos, err := c.metadataClient.Resource(flexTopoGVR).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+	nodes, err := c.metadataClient.Resource(nodeGVR).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+	nodeNames := make(map[string]bool, len(nodes.Items))
+	for _, node := range nodes.Items {
+		nodeNames[node.GetName()] = true
+	}
+
+	deleted := 0
+	var errs []error
+	for _, flextopo := range flextopos.Items {
+		if nodeNames[flextopo.GetName()] {
+			continue
+		}
+		// Only delete the listed FlexTopo, not one created since for a new Node of that name
+		uid := flextopo.GetUID()
+		err := c.metadataClient.Resource(flexTopoGVR).Delete(context.TODO(), flextopo.GetName(), metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &uid},
+		})
+		if apierrors.IsNotFound(err) || apierrors.IsConflict(err) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		c.logger.Infof("Deleted FlexTopo %s, its node no longer exists", flextopo.GetName())
+		deleted++
+	}
+	return deleted, errors.Join(errs...)
+}
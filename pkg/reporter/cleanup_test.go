@@ -0,0 +1,51 @@
+package reporter
+
+import (
+	"context"
+	"flextopo/pkg/crd"
+	"flextopo/pkg/utils"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+// newMetadata returns the metadata of an object as listed by the metadata client
+func newMetadata(apiVersion, kind, name string) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: apiVersion, Kind: kind},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}
+}
+
+func TestCleanupOrphans(t *testing.T) {
+	scheme := metadatafake.NewTestScheme()
+	if err := metav1.AddMetaToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	flexTopoVersion := crd.SchemeGroupVersion.String()
+	client := metadatafake.NewSimpleMetadataClient(scheme,
+		newMetadata(flexTopoVersion, "FlexTopo", "node-1"),
+		newMetadata(flexTopoVersion, "FlexTopo", "node-2"),
+		newMetadata(flexTopoVersion, "FlexTopo", "node-3"),
+		newMetadata("v1", "Node", "node-1"),
+	)
+	cleaner := newCleaner(client, &utils.SimpleLogger{})
+
+	deleted, err := cleaner.CleanupOrphans()
+	if err != nil || deleted != 2 {
+		t.Fatalf("CleanupOrphans() = %d, %v, want 2, nil", deleted, err)
+	}
+	flextopos, err := client.Resource(flexTopoGVR).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(flextopos.Items) != 1 || flextopos.Items[0].GetName() != "node-1" {
+		t.Errorf("remaining FlexTopos = %v, want node-1", flextopos.Items)
+	}
+
+	// Nothing is left to delete
+	if deleted, err := cleaner.CleanupOrphans(); err != nil || deleted != 0 {
+		t.Errorf("second CleanupOrphans() = %d, %v, want 0, nil", deleted, err)
+	}
+}
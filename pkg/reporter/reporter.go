@@ -2,26 +2,71 @@ package reporter
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flextopo/pkg/collector"
 	"flextopo/pkg/crd"
 	"flextopo/pkg/graph"
 	"flextopo/pkg/utils"
+	"time"
 
-	"k8s.io/apimachinery/pkg/api/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
 )
 
+// flexTopoGVR identifies the FlexTopo resource
+var flexTopoGVR = crd.SchemeGroupVersion.WithResource("flextopos")
+
+// nodeGVR identifies the Node resource, the owner of every FlexTopo
+var nodeGVR = corev1.SchemeGroupVersion.WithResource("nodes")
+
+// fieldManager owns the fields of the FlexTopo that the agent applies
+const fieldManager = "flextopo-agent"
+
+// maxConditionMessage bounds the length of condition messages and collector errors,
+// validation can report one violation per node
+const maxConditionMessage = 4096
+
 type Reporter struct {
 	dynamicClient dynamic.Interface
 	nodeName      string
 	logger        utils.Logger
+	// topologyOptions controls the layout of the reported topology
+	topologyOptions graph.TopologyOptions
+	// compactThreshold is the topology size in bytes above which the compact layout is used
+	compactThreshold int
+	// compact records whether the last report used the compact layout
+	compact bool
+	// resyncInterval is the longest time an unchanged status is not written
+	resyncInterval time.Duration
+
+	// status is the last applied status, nil until the first report reads it from the server
+	status *crd.FlexTopoStatus
+	// generation is the generation of the FlexTopo when its status was last read or applied
+	generation int64
+	// topologyHash is the hash of the last reported topology without volatile attributes
+	topologyHash string
+	// statusHash is the hash of the last applied status, see hashStatus
+	statusHash string
+	// lastApplied is the collection time of the last applied status
+	lastApplied time.Time
+	// owned records whether the Node of this node was made the owner of its FlexTopo
+	owned bool
 }
 
-func NewReporter(nodeName string, logger utils.Logger) (*Reporter, error) {
+// NewReporter creates a Reporter for the FlexTopo of nodeName that reports the topology in
+// the layout given by options. The compact layout is chosen per report from the size of the
+// topology, options.Compact is ignored.
+func NewReporter(nodeName string, options graph.TopologyOptions, logger utils.Logger) (*Reporter, error) {
 	config, err := rest.InClusterConfig()
 	if err != nil {
 		return nil, err
@@ -30,66 +75,317 @@ func NewReporter(nodeName string, logger utils.Logger) (*Reporter, error) {
 	if err != nil {
 		return nil, err
 	}
+	return newReporter(dynamicClient, nodeName, options, logger), nil
+}
+
+// newReporter creates a Reporter that writes through dynamicClient
+func newReporter(dynamicClient dynamic.Interface, nodeName string, options graph.TopologyOptions, logger utils.Logger) *Reporter {
+	options.Compact = false
 	return &Reporter{
-		dynamicClient: dynamicClient,
-		nodeName:      nodeName,
-		logger:        logger,
-	}, nil
+		dynamicClient:    dynamicClient,
+		nodeName:         nodeName,
+		logger:           logger,
+		topologyOptions:  options,
+		compactThreshold: utils.GetConfig().SpecCompactThreshold,
+		resyncInterval:   utils.GetConfig().StatusResyncInterval,
+	}
 }
 
-func (r *Reporter) Report(graph *graph.FlexTopoGraph) error {
-	gvr := schema.GroupVersionResource{
-		Group:    "flextopo.baichuan-inc.com",
-		Version:  "v1alpha1",
-		Resource: "flextopos",
+// Report publishes a collection cycle in the status of the FlexTopo of this node. The
+// topology is only replaced if it was collected and passed validation, otherwise the status
+// keeps the last valid topology and the conditions tell why. The status is only written if it
+// changed, apart from volatile attributes, or the resync interval passed. The FlexTopo is
+// owned by the Node, so that it is garbage collected with the Node.
+func (r *Reporter) Report(collection *collector.Collection) error {
+	conditions := collectionConditions(collection)
+	var topology *crd.FlexTopoTopology
+	if collection.Graph == nil {
+		conditions = append(conditions, metav1.Condition{
+			Type:    crd.ConditionReported,
+			Status:  metav1.ConditionFalse,
+			Reason:  "CollectionFailed",
+			Message: "The status keeps the last valid topology",
+		})
+	} else if err := errors.Join(collection.Graph.Validate(), collection.Graph.ValidateAttributes()); err != nil {
+		// Keep the last valid topology published and surface why this one was rejected
+		r.logger.Error("Collected topology is invalid: " + err.Error())
+		conditions = append(conditions, metav1.Condition{
+			Type:    crd.ConditionTopologyValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ValidationFailed",
+			Message: truncateMessage(err.Error()),
+		}, metav1.Condition{
+			Type:    crd.ConditionReported,
+			Status:  metav1.ConditionFalse,
+			Reason:  "TopologyInvalid",
+			Message: "The status keeps the last valid topology",
+		})
+	} else {
+		if topology, err = r.renderTopology(collection.Graph); err != nil {
+			return err
+		}
+		// Volatile attributes change on almost every cycle, they alone do not warrant a write
+		stable := collection.Graph.StableSnapshot()
+		options := r.topologyOptions
+		options.Compact = r.compact
+		if r.topologyHash, err = hash(stable.ToTopologyWithOptions(options)); err != nil {
+			return err
+		}
+		conditions = append(conditions, metav1.Condition{
+			Type:    crd.ConditionTopologyValid,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Validated",
+			Message: "The topology passed validation",
+		}, metav1.Condition{
+			Type:    crd.ConditionReported,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Reported",
+			Message: "The status holds the topology of the last collection",
+		})
 	}
 
-	// Convert topology graph to CRD Spec
-	spec := graph.ToSpec()
+	var collectorErrors []string
+	for _, err := range collection.Errors {
+		collectorErrors = append(collectorErrors, truncateMessage(err.Error()))
+	}
 
-	// Build CRD object
-	flextopo := &crd.FlexTopo{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "FlexTopo",
-			APIVersion: "flextopo.baichuan-inc.com/v1alpha1",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name: r.nodeName, // Use node name as the CRD object name
-		},
-		Spec: *spec,
+	if r.status == nil {
+		if err := r.readStatus(); err != nil {
+			return err
+		}
+	}
+	status := r.status.DeepCopy()
+	if topology != nil {
+		status.FlexTopoTopology = *topology
+	}
+	status.ObservedGeneration = r.generation
+	status.AgentVersion = utils.Version
+	status.CollectorErrors = collectorErrors
+	for _, condition := range conditions {
+		condition.ObservedGeneration = r.generation
+		meta.SetStatusCondition(&status.Conditions, condition)
 	}
 
-	// Convert CRD object to unstructured.Unstructured
-	unstructuredData, err := runtime.DefaultUnstructuredConverter.ToUnstructured(flextopo)
+	statusHash, err := r.hashStatus(status)
 	if err != nil {
 		return err
 	}
-	unstructuredObj := &unstructured.Unstructured{Object: unstructuredData}
+	if statusHash != r.statusHash || collection.Time.Sub(r.lastApplied) >= r.resyncInterval {
+		status.LastCollectionTime = &metav1.Time{Time: collection.Time}
+		if err := r.applyStatus(status); err != nil {
+			return err
+		}
+		r.status, r.statusHash, r.lastApplied = status, statusHash, collection.Time
+		if topology != nil {
+			r.logger.Info("Successfully reported topology data")
+		}
+	}
+
+	if !r.owned {
+		return r.setOwner()
+	}
+	return nil
+}
+
+// collectionConditions returns the HardwareCollected and AllocationsCollected conditions
+func collectionConditions(collection *collector.Collection) []metav1.Condition {
+	hardware := metav1.Condition{
+		Type:    crd.ConditionHardwareCollected,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Collected",
+		Message: "The hardware topology was collected",
+	}
+	allocations := metav1.Condition{
+		Type:    crd.ConditionAllocationsCollected,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Collected",
+		Message: "The resource allocations were collected",
+	}
+	if err := collection.Failed(collector.HardwareCollectorName); err != nil {
+		hardware.Status, hardware.Reason, hardware.Message = metav1.ConditionFalse, "CollectionFailed", truncateMessage(err.Error())
+		// Allocations are placed on the hardware topology and were not collected
+		allocations.Status, allocations.Reason, allocations.Message = metav1.ConditionUnknown, "HardwareNotCollected", "The hardware topology could not be collected"
+	} else if err := collection.Failed(collector.ResourceCollectorName); err != nil {
+		allocations.Status, allocations.Reason, allocations.Message = metav1.ConditionFalse, "CollectionFailed", truncateMessage(err.Error())
+	}
+	return []metav1.Condition{hardware, allocations}
+}
 
-	// Try to get existing resource
-	existing, err := r.dynamicClient.Resource(gvr).Get(context.TODO(), r.nodeName, metav1.GetOptions{})
+// truncateMessage bounds message to maxConditionMessage bytes
+func truncateMessage(message string) string {
+	if len(message) > maxConditionMessage {
+		return message[:maxConditionMessage-3] + "..."
+	}
+	return message
+}
+
+// renderTopology renders the topology in the configured layout, and in the compact layout if
+// the topology would exceed the compact threshold
+func (r *Reporter) renderTopology(topology *graph.FlexTopoGraph) (*crd.FlexTopoTopology, error) {
+	rendered := topology.ToTopologyWithOptions(r.topologyOptions)
+	data, err := json.Marshal(rendered)
 	if err != nil {
-		if !errors.IsNotFound(err) {
-			r.logger.Error("Failed to get FlexTopo CRD: " + err.Error())
-			return err
+		return nil, err
+	}
+	compact := len(data) > r.compactThreshold
+	if compact {
+		options := r.topologyOptions
+		options.Compact = true
+		rendered = topology.ToTopologyWithOptions(options)
+	}
+	if compact != r.compact {
+		r.compact = compact
+		if compact {
+			r.logger.Infof("Topology of %d bytes exceeds %d bytes, switching to the compact layout", len(data), r.compactThreshold)
+		} else {
+			r.logger.Infof("Topology of %d bytes fits within %d bytes, switching to the full layout", len(data), r.compactThreshold)
 		}
-		// Resource doesn't exist, create a new one
-		_, err = r.dynamicClient.Resource(gvr).Create(context.TODO(), unstructuredObj, metav1.CreateOptions{})
-		if err != nil {
-			r.logger.Error("Failed to create FlexTopo CRD: " + err.Error())
+	}
+	return rendered, nil
+}
+
+// readStatus reads the status and generation of the FlexTopo of this node, so that the first
+// report keeps the topology and condition transition times of the previous agent
+func (r *Reporter) readStatus() error {
+	existing, err := r.dynamicClient.Resource(flexTopoGVR).Get(context.TODO(), r.nodeName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		r.status = &crd.FlexTopoStatus{}
+		return nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to get FlexTopo CRD: " + err.Error())
+		return err
+	}
+
+	status := &crd.FlexTopoStatus{}
+	if rawStatus, ok := existing.Object["status"].(map[string]interface{}); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(rawStatus, status); err != nil {
 			return err
 		}
-		r.logger.Info("Successfully created FlexTopo CRD for node: " + r.nodeName)
-	} else {
-		// Resource exists, update it
-		existing.Object["spec"] = unstructuredObj.Object["spec"]
-		_, err = r.dynamicClient.Resource(gvr).Update(context.TODO(), existing, metav1.UpdateOptions{})
+	}
+	r.status, r.generation = status, existing.GetGeneration()
+	return nil
+}
+
+// hashStatus hashes status without the collection time, and with the topology hash of the
+// last reported topology in place of the topology
+func (r *Reporter) hashStatus(status *crd.FlexTopoStatus) (string, error) {
+	hashed := *status
+	hashed.FlexTopoTopology = crd.FlexTopoTopology{}
+	hashed.LastCollectionTime = nil
+	return hash(struct {
+		Topology string              `json:"topology"`
+		Status   *crd.FlexTopoStatus `json:"status"`
+	}{r.topologyHash, &hashed})
+}
+
+// hash returns the SHA-256 of the JSON encoding of value
+func hash(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// applyStatus writes status with server-side apply, so that fields owned by others are left
+// alone, and creates the FlexTopo of this node if it does not exist. The agent is the only
+// writer of the status and forces its ownership, so applies do not conflict. Throttling
+// and timeouts of the API server are retried with backoff.
+func (r *Reporter) applyStatus(status *crd.FlexTopoStatus) error {
+	rawStatus, err := runtime.DefaultUnstructuredConverter.ToUnstructured(status)
+	if err != nil {
+		return err
+	}
+	flextopo := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": crd.SchemeGroupVersion.String(),
+		"kind":       "FlexTopo",
+		"metadata":   map[string]interface{}{"name": r.nodeName}, // Use node name as the CRD object name
+		"status":     rawStatus,
+	}}
+	options := metav1.ApplyOptions{FieldManager: fieldManager, Force: true}
+
+	resource := r.dynamicClient.Resource(flexTopoGVR)
+	err = retry.OnError(retry.DefaultBackoff, isTransient, func() error {
+		applied, err := resource.ApplyStatus(context.TODO(), r.nodeName, flextopo, options)
+		if apierrors.IsNotFound(err) {
+			// The status subresource cannot create the FlexTopo
+			if err := r.create(); err != nil {
+				return err
+			}
+			applied, err = resource.ApplyStatus(context.TODO(), r.nodeName, flextopo, options)
+		}
 		if err != nil {
-			r.logger.Error("Failed to update FlexTopo CRD: " + err.Error())
 			return err
 		}
-		r.logger.Info("Successfully updated FlexTopo CRD for node: " + r.nodeName)
+		r.generation = applied.GetGeneration()
+		return nil
+	})
+	if err != nil {
+		r.logger.Error("Failed to apply FlexTopo status: " + err.Error())
+	}
+	return err
+}
+
+// setOwner makes the Node of this node the owner of its FlexTopo. FlexTopos created by older
+// agents have no owner, and a FlexTopo is recreated without one if its Node was replaced.
+func (r *Reporter) setOwner() error {
+	node, err := r.dynamicClient.Resource(nodeGVR).Get(context.TODO(), r.nodeName, metav1.GetOptions{})
+	if err != nil {
+		r.logger.Error("Failed to get Node: " + err.Error())
+		return err
+	}
+	flextopo := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": crd.SchemeGroupVersion.String(),
+		"kind":       "FlexTopo",
+		"metadata":   map[string]interface{}{"name": r.nodeName},
+	}}
+	flextopo.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: corev1.SchemeGroupVersion.String(),
+		Kind:       "Node",
+		Name:       node.GetName(),
+		UID:        node.GetUID(),
+	}})
+	options := metav1.ApplyOptions{FieldManager: fieldManager, Force: true}
+
+	err = retry.OnError(retry.DefaultBackoff, isTransient, func() error {
+		_, err := r.dynamicClient.Resource(flexTopoGVR).Apply(context.TODO(), r.nodeName, flextopo, options)
+		return err
+	})
+	if err != nil {
+		r.logger.Error("Failed to set the owner of the FlexTopo: " + err.Error())
+		return err
 	}
+	r.owned = true
+	return nil
+}
+
+// isTransient reports whether err is a throttling or timeout error of the API server, which
+// is worth retrying
+func isTransient(err error) bool {
+	return apierrors.IsTooManyRequests(err) || apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) || apierrors.IsServiceUnavailable(err)
+}
 
+// create creates the FlexTopo of this node with an empty spec, it may already exist
+func (r *Reporter) create() error {
+	flextopo := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": crd.SchemeGroupVersion.String(),
+		"kind":       "FlexTopo",
+		"metadata":   map[string]interface{}{"name": r.nodeName},
+		"spec":       map[string]interface{}{},
+	}}
+	_, err := r.dynamicClient.Resource(flexTopoGVR).Create(context.TODO(), flextopo, metav1.CreateOptions{FieldManager: fieldManager})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to create FlexTopo CRD: " + err.Error())
+		return err
+	}
+	r.logger.Info("Successfully created FlexTopo CRD for node: " + r.nodeName)
+	// The owner is set by the next report
+	r.owned = false
 	return nil
 }
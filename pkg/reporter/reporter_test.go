@@ -0,0 +1,304 @@
+package reporter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"flextopo/pkg/collector"
+	"flextopo/pkg/crd"
+	"flextopo/pkg/graph"
+	"flextopo/pkg/utils"
+)
+
+// newFakeClient returns a fake dynamic client for FlexTopos and Nodes. The object tracker
+// applies with a strategic merge patch, which does not support unstructured objects, so
+// applies replace the status or the owner references instead. The agent owns both.
+func newFakeClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{flexTopoGVR: "FlexTopoList", nodeGVR: "NodeList"}, objects...)
+	client.PrependReactor("patch", "flextopos", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		applied := &unstructured.Unstructured{}
+		if err := applied.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		existing, err := client.Tracker().Get(flexTopoGVR, "", patch.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		flextopo := existing.(*unstructured.Unstructured).DeepCopy()
+		if patch.GetSubresource() == "status" {
+			flextopo.Object["status"] = applied.Object["status"]
+		} else {
+			flextopo.SetOwnerReferences(applied.GetOwnerReferences())
+		}
+		if err := client.Tracker().Update(flexTopoGVR, flextopo, ""); err != nil {
+			return true, nil, err
+		}
+		return true, flextopo, nil
+	})
+	return client
+}
+
+// newNode returns a Node
+func newNode(name string, uid types.UID) *unstructured.Unstructured {
+	node := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Node",
+		"metadata":   map[string]interface{}{"name": name},
+	}}
+	node.SetUID(uid)
+	return node
+}
+
+// newFlexTopo returns an existing FlexTopo without status
+func newFlexTopo(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": crd.SchemeGroupVersion.String(),
+		"kind":       "FlexTopo",
+		"metadata":   map[string]interface{}{"name": name, "generation": int64(1)},
+		"spec":       map[string]interface{}{},
+	}}
+}
+
+// newTestGraph returns a graph of four cores, with the given cores used by a pod and
+// utilization on every core
+func newTestGraph(utilization float64, usedCores ...int) *graph.FlexTopoGraph {
+	return newConsumerGraph(utilization, graph.Consumer{Pod: "pod-a", Namespace: "default"}, usedCores...)
+}
+
+// newConsumerGraph returns a graph of four cores, with the given cores used by consumer and
+// utilization on every core
+func newConsumerGraph(utilization float64, consumer graph.Consumer, usedCores ...int) *graph.FlexTopoGraph {
+	topology := graph.NewFlexTopoGraph(2)
+	var cpuInfos []utils.CPUInfo
+	for cpu := 0; cpu < 4; cpu++ {
+		cpuInfos = append(cpuInfos, utils.CPUInfo{CPUID: cpu, CoreID: cpu})
+	}
+	topology.BuildCPUNodes(cpuInfos)
+	if len(usedCores) > 0 {
+		topology.UpdateCPUUsage(consumer, usedCores)
+	}
+	topology.UpdateCoreUtilization(map[int]float64{0: utilization, 1: utilization, 2: utilization, 3: utilization})
+	topology.UpdateRollups()
+	return topology.Snapshot()
+}
+
+// statusApplies counts the server-side applies of the status
+func statusApplies(client *dynamicfake.FakeDynamicClient) int {
+	applies := 0
+	for _, action := range client.Actions() {
+		if patch, ok := action.(k8stesting.PatchAction); ok && patch.GetSubresource() == "status" &&
+			patch.GetPatchType() == types.ApplyPatchType {
+			applies++
+		}
+	}
+	return applies
+}
+
+func getStatus(t *testing.T, client *dynamicfake.FakeDynamicClient) *crd.FlexTopoStatus {
+	t.Helper()
+	flextopo, err := client.Resource(flexTopoGVR).Get(context.TODO(), "node-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	status := &crd.FlexTopoStatus{}
+	rawStatus, _ := flextopo.Object["status"].(map[string]interface{})
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(rawStatus, status); err != nil {
+		t.Fatalf("invalid status: %v", err)
+	}
+	return status
+}
+
+func TestReport(t *testing.T) {
+	client := newFakeClient(newNode("node-1", "uid-1"))
+	reporter := newReporter(client, "node-1", graph.TopologyOptions{}, &utils.SimpleLogger{})
+	start := time.Date(2024, 10, 10, 7, 31, 23, 0, time.UTC)
+
+	if err := reporter.Report(&collector.Collection{Graph: newTestGraph(10, 0), Time: start}); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	// The status apply fails until the FlexTopo is created, then the Node becomes its owner
+	var verbs []string
+	for _, action := range client.Actions() {
+		verbs = append(verbs, action.GetVerb()+" "+action.GetResource().Resource)
+	}
+	want := []string{"get flextopos", "patch flextopos", "create flextopos", "patch flextopos", "get nodes", "patch flextopos"}
+	if !reflect.DeepEqual(verbs, want) {
+		t.Errorf("actions = %v, want %v", verbs, want)
+	}
+	status := getStatus(t, client)
+	if status.Summary == nil || status.Summary.CoresUsed != 1 || status.AgentVersion != utils.Version ||
+		!status.LastCollectionTime.Time.Equal(start) {
+		t.Errorf("status = %+v", status)
+	}
+	for _, conditionType := range []string{crd.ConditionHardwareCollected, crd.ConditionAllocationsCollected, crd.ConditionTopologyValid, crd.ConditionReported} {
+		if !meta.IsStatusConditionTrue(status.Conditions, conditionType) {
+			t.Errorf("condition %s is not true: %+v", conditionType, status.Conditions)
+		}
+	}
+
+	// A failed collection keeps the topology and reports why
+	failed := &collector.Collection{
+		Time:   start.Add(5 * time.Second),
+		Errors: []*collector.CollectorError{{Collector: collector.ResourceCollectorName, Err: errors.New("kubelet unreachable")}},
+	}
+	if err := reporter.Report(failed); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	status = getStatus(t, client)
+	if status.Summary == nil || status.Summary.CoresUsed != 1 || len(status.Nodes) == 0 {
+		t.Errorf("the failed collection replaced the topology: %+v", status.FlexTopoTopology)
+	}
+	if len(status.CollectorErrors) != 1 || status.CollectorErrors[0] != "resource collector: kubelet unreachable" {
+		t.Errorf("CollectorErrors = %v", status.CollectorErrors)
+	}
+	if !meta.IsStatusConditionFalse(status.Conditions, crd.ConditionAllocationsCollected) ||
+		!meta.IsStatusConditionFalse(status.Conditions, crd.ConditionReported) ||
+		!meta.IsStatusConditionTrue(status.Conditions, crd.ConditionHardwareCollected) {
+		t.Errorf("Conditions = %+v", status.Conditions)
+	}
+}
+
+func TestReportSkipsUnchanged(t *testing.T) {
+	client := newFakeClient(newFlexTopo("node-1"), newNode("node-1", "uid-1"))
+	reporter := newReporter(client, "node-1", graph.TopologyOptions{}, &utils.SimpleLogger{})
+	reporter.resyncInterval = time.Minute
+	start := time.Date(2024, 10, 10, 7, 31, 23, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		graph   *graph.FlexTopoGraph
+		elapsed time.Duration
+		applies int
+	}{
+		{name: "first report", graph: newTestGraph(10, 0), applies: 1},
+		{name: "unchanged", graph: newTestGraph(10, 0), elapsed: 5 * time.Second, applies: 1},
+		{name: "only utilization changed", graph: newTestGraph(90, 0), elapsed: 10 * time.Second, applies: 1},
+		{name: "allocation changed", graph: newTestGraph(90, 0, 1), elapsed: 15 * time.Second, applies: 2},
+		{name: "resync", graph: newTestGraph(90, 0, 1), elapsed: 75 * time.Second, applies: 3},
+		{name: "only consumer usage changed", graph: newConsumerGraph(90, graph.Consumer{
+			Pod: "pod-a", Namespace: "default", CPUUsage: 1.5, NUMAMemory: map[int]int64{0: 1 << 20},
+		}, 0, 1), elapsed: 80 * time.Second, applies: 3},
+		{name: "memsAllowed changed", graph: newConsumerGraph(90, graph.Consumer{
+			Pod: "pod-a", Namespace: "default", MemsAllowed: []int{0},
+		}, 0, 1), elapsed: 85 * time.Second, applies: 4},
+		{name: "pod replaced", graph: newConsumerGraph(90, graph.Consumer{
+			Pod: "pod-b", Namespace: "default", MemsAllowed: []int{0},
+		}, 0, 1), elapsed: 90 * time.Second, applies: 5},
+	}
+	for _, tt := range tests {
+		if err := reporter.Report(&collector.Collection{Graph: tt.graph, Time: start.Add(tt.elapsed)}); err != nil {
+			t.Fatalf("%s: Report() error = %v", tt.name, err)
+		}
+		if got := statusApplies(client); got != tt.applies {
+			t.Errorf("%s: %d status applies, want %d", tt.name, got, tt.applies)
+		}
+	}
+	if status := getStatus(t, client); !status.LastCollectionTime.Time.Equal(start.Add(90 * time.Second)) {
+		t.Errorf("LastCollectionTime = %v, want the time of the last write", status.LastCollectionTime)
+	}
+}
+
+func TestReportLayout(t *testing.T) {
+	for _, nested := range []bool{false, true} {
+		client := newFakeClient(newFlexTopo("node-1"), newNode("node-1", "uid-1"))
+		reporter := newReporter(client, "node-1", graph.TopologyOptions{Nested: nested}, &utils.SimpleLogger{})
+		if err := reporter.Report(&collector.Collection{Graph: newTestGraph(10), Time: time.Now()}); err != nil {
+			t.Fatalf("Report() error = %v", err)
+		}
+		// The nested layout lists only root nodes, the flat layout every node
+		topLevelCores := 0
+		for _, node := range getStatus(t, client).Nodes {
+			if node.Type == "CPUCore" {
+				topLevelCores++
+			}
+		}
+		if want := map[bool]int{false: 4, true: 0}[nested]; topLevelCores != want {
+			t.Errorf("nested = %v: %d top-level cores, want %d", nested, topLevelCores, want)
+		}
+	}
+}
+
+func TestReportRetriesTransientErrors(t *testing.T) {
+	client := newFakeClient(newFlexTopo("node-1"), newNode("node-1", "uid-1"))
+	failures := []error{
+		apierrors.NewTooManyRequests("too many requests", 1),
+		apierrors.NewServerTimeout(flexTopoGVR.GroupResource(), "patch", 1),
+	}
+	client.PrependReactor("patch", "flextopos", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if len(failures) == 0 {
+			return false, nil, nil
+		}
+		err := failures[0]
+		failures = failures[1:]
+		return true, nil, err
+	})
+	reporter := newReporter(client, "node-1", graph.TopologyOptions{}, &utils.SimpleLogger{})
+
+	if err := reporter.Report(&collector.Collection{Graph: newTestGraph(10), Time: time.Now()}); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if got := statusApplies(client); got != 3 {
+		t.Errorf("%d status applies, want 3", got)
+	}
+	if status := getStatus(t, client); status.Summary == nil || status.Summary.CoresTotal != 4 || status.ObservedGeneration != 1 {
+		t.Errorf("status = %+v", status)
+	}
+
+	// Other errors are returned without retrying
+	client.PrependReactor("patch", "flextopos", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(flexTopoGVR.GroupResource(), "node-1", errors.New("denied"))
+	})
+	client.ClearActions()
+	err := reporter.Report(&collector.Collection{Graph: newTestGraph(10, 1), Time: time.Now()})
+	if !apierrors.IsForbidden(err) || statusApplies(client) != 1 {
+		t.Errorf("Report() error = %v after %d applies, want Forbidden after 1", err, statusApplies(client))
+	}
+}
+
+func TestReportOwnerReference(t *testing.T) {
+	client := newFakeClient(newFlexTopo("node-1"), newNode("node-1", "uid-1"))
+	reporter := newReporter(client, "node-1", graph.TopologyOptions{}, &utils.SimpleLogger{})
+	start := time.Date(2024, 10, 10, 7, 31, 23, 0, time.UTC)
+
+	// The owner is set once, also when a report skips the write of the status
+	for cycle := 0; cycle < 2; cycle++ {
+		if err := reporter.Report(&collector.Collection{Graph: newTestGraph(10), Time: start.Add(time.Duration(cycle) * time.Second)}); err != nil {
+			t.Fatalf("Report() error = %v", err)
+		}
+	}
+	flextopo, err := client.Resource(flexTopoGVR).Get(context.TODO(), "node-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	want := []metav1.OwnerReference{{APIVersion: "v1", Kind: "Node", Name: "node-1", UID: "uid-1"}}
+	if got := flextopo.GetOwnerReferences(); !reflect.DeepEqual(got, want) {
+		t.Errorf("OwnerReferences = %+v, want %+v", got, want)
+	}
+	nodeGets := 0
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "get" && action.GetResource() == nodeGVR {
+			nodeGets++
+		}
+	}
+	if nodeGets != 1 {
+		t.Errorf("the Node was read %d times, want once", nodeGets)
+	}
+}
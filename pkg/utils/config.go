@@ -3,11 +3,46 @@ package utils
 import (
 	"os"
 	"strconv"
+	"time"
+)
+
+// Sources the resource collector can read Pods from
+const (
+	PodSourceAPIServer = "apiserver"
+	PodSourceKubelet   = "kubelet"
+)
+
+// Layouts of the reported FlexTopo topology
+const (
+	// SpecLayoutFlat lists all nodes at the top level
+	SpecLayoutFlat = "flat"
+	// SpecLayoutNested nests the Socket, NUMANode, CoreGroup and CPUCore hierarchy in children
+	SpecLayoutNested = "nested"
 )
 
 // Config is the global configuration
 type Config struct {
 	CoreGroupSize int
+	// PodSource selects where Pods on this node are read from, "apiserver" or "kubelet"
+	PodSource string
+	// KubeletURL is the base URL of the kubelet, its /pods endpoint is queried
+	KubeletURL string
+	// KubeletTokenFile holds the bearer token sent to an https kubelet endpoint
+	KubeletTokenFile string
+	// KubeletCAFile is used to verify the kubelet serving certificate of an https endpoint
+	KubeletCAFile string
+	// KubeletInsecureSkipVerify disables verification of the kubelet serving certificate
+	KubeletInsecureSkipVerify bool
+	// ReservedCPUs are the cores reserved for system daemons, e.g. the kubelet --reserved-cpus
+	ReservedCPUs CPUSet
+	// SpecLayout is the layout of the reported topology, "flat" or "nested"
+	SpecLayout string
+	// SpecCompactThreshold is the size in bytes of the rendered topology above which the compact
+	// layout is reported, which folds cores into the core lists of their core group
+	SpecCompactThreshold int
+	// StatusResyncInterval is the longest time an unchanged status is not written, volatile
+	// attributes such as utilization are only refreshed this often
+	StatusResyncInterval time.Duration
 	// other configurations
 }
 
@@ -23,8 +58,71 @@ func GetConfig() *Config {
 			}
 		}
 
+		podSource := PodSourceAPIServer // default value
+		// Other values are passed through, so that NewPodSource rejects them
+		if val := os.Getenv("POD_SOURCE"); val != "" {
+			podSource = val
+		}
+
+		kubeletURL := "http://127.0.0.1:10255" // default value, the read-only port
+		if val := os.Getenv("KUBELET_URL"); val != "" {
+			kubeletURL = val
+		}
+
+		kubeletTokenFile := "/var/run/secrets/kubernetes.io/serviceaccount/token" // default value
+		if val := os.Getenv("KUBELET_TOKEN_FILE"); val != "" {
+			kubeletTokenFile = val
+		}
+
+		kubeletCAFile := "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt" // default value
+		if val := os.Getenv("KUBELET_CA_FILE"); val != "" {
+			kubeletCAFile = val
+		}
+
+		kubeletInsecureSkipVerify := false // default value
+		if val := os.Getenv("KUBELET_INSECURE_SKIP_VERIFY"); val != "" {
+			if skip, err := strconv.ParseBool(val); err == nil {
+				kubeletInsecureSkipVerify = skip
+			}
+		}
+
+		var reservedCPUs CPUSet // default value, none
+		if val := os.Getenv("RESERVED_CPUS"); val != "" {
+			if cpus, err := ParseCPUSet(val); err == nil {
+				reservedCPUs = cpus
+			}
+		}
+
+		specLayout := SpecLayoutFlat // default value
+		if val := os.Getenv("SPEC_LAYOUT"); val == SpecLayoutNested {
+			specLayout = val
+		}
+
+		specCompactThreshold := 512 * 1024 // default value, a third of the default etcd request limit
+		if val := os.Getenv("SPEC_COMPACT_THRESHOLD"); val != "" {
+			if threshold, err := strconv.Atoi(val); err == nil && threshold >= 0 {
+				specCompactThreshold = threshold
+			}
+		}
+
+		statusResyncInterval := 5 * time.Minute // default value
+		if val := os.Getenv("STATUS_RESYNC_INTERVAL"); val != "" {
+			if interval, err := time.ParseDuration(val); err == nil && interval >= 0 {
+				statusResyncInterval = interval
+			}
+		}
+
 		config = &Config{
-			CoreGroupSize: coreGroupSize,
+			CoreGroupSize:             coreGroupSize,
+			PodSource:                 podSource,
+			KubeletURL:                kubeletURL,
+			KubeletTokenFile:          kubeletTokenFile,
+			KubeletCAFile:             kubeletCAFile,
+			KubeletInsecureSkipVerify: kubeletInsecureSkipVerify,
+			ReservedCPUs:              reservedCPUs,
+			SpecLayout:                specLayout,
+			SpecCompactThreshold:      specCompactThreshold,
+			StatusResyncInterval:      statusResyncInterval,
 		}
 	}
 	return config
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CPUSet is an immutable set of CPU numbers, such as the cpuset of a cgroup or the cores of
+// a core group. The zero value is the empty set.
+type CPUSet struct {
+	// cpus are sorted and unique
+	cpus []int
+}
+
+// NewCPUSet returns the set of the given CPUs, which may be unsorted and contain duplicates
+func NewCPUSet(cpus ...int) CPUSet {
+	sorted := append([]int(nil), cpus...)
+	sort.Ints(sorted)
+	unique := sorted[:0]
+	for i, cpu := range sorted {
+		if i == 0 || cpu != sorted[i-1] {
+			unique = append(unique, cpu)
+		}
+	}
+	return CPUSet{cpus: unique}
+}
+
+// ParseCPUSet parses a CPU list in the syntax accepted by ParseCPUList, e.g. "0-7,64-71"
+func ParseCPUSet(s string) (CPUSet, error) {
+	cpus, err := ParseCPUList(s)
+	if err != nil {
+		return CPUSet{}, err
+	}
+	return NewCPUSet(cpus...), nil
+}
+
+// String formats the set in the canonical kernel list format: ascending, with runs of two
+// or more consecutive CPUs as ranges, e.g. "0-3,8,10-11". The empty set is "".
+func (s CPUSet) String() string {
+	var b strings.Builder
+	for i := 0; i < len(s.cpus); {
+		start, end := s.cpus[i], s.cpus[i]
+		for i++; i < len(s.cpus) && s.cpus[i] == end+1; i++ {
+			end = s.cpus[i]
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		if start == end {
+			b.WriteString(strconv.Itoa(start))
+		} else {
+			fmt.Fprintf(&b, "%d-%d", start, end)
+		}
+	}
+	return b.String()
+}
+
+// Size returns the number of CPUs in the set
+func (s CPUSet) Size() int {
+	return len(s.cpus)
+}
+
+// IsEmpty reports whether the set has no CPUs
+func (s CPUSet) IsEmpty() bool {
+	return len(s.cpus) == 0
+}
+
+// Contains reports whether cpu is in the set
+func (s CPUSet) Contains(cpu int) bool {
+	i := sort.SearchInts(s.cpus, cpu)
+	return i < len(s.cpus) && s.cpus[i] == cpu
+}
+
+// Equals reports whether both sets have the same CPUs
+func (s CPUSet) Equals(other CPUSet) bool {
+	if len(s.cpus) != len(other.cpus) {
+		return false
+	}
+	for i, cpu := range s.cpus {
+		if other.cpus[i] != cpu {
+			return false
+		}
+	}
+	return true
+}
+
+// Union returns the CPUs in s or in any of others
+func (s CPUSet) Union(others ...CPUSet) CPUSet {
+	cpus := append([]int(nil), s.cpus...)
+	for _, other := range others {
+		cpus = append(cpus, other.cpus...)
+	}
+	return NewCPUSet(cpus...)
+}
+
+// Intersection returns the CPUs in both s and other
+func (s CPUSet) Intersection(other CPUSet) CPUSet {
+	var cpus []int
+	for i, j := 0, 0; i < len(s.cpus) && j < len(other.cpus); {
+		switch {
+		case s.cpus[i] < other.cpus[j]:
+			i++
+		case s.cpus[i] > other.cpus[j]:
+			j++
+		default:
+			cpus = append(cpus, s.cpus[i])
+			i++
+			j++
+		}
+	}
+	return CPUSet{cpus: cpus}
+}
+
+// Difference returns the CPUs in s that are not in other
+func (s CPUSet) Difference(other CPUSet) CPUSet {
+	var cpus []int
+	for _, cpu := range s.cpus {
+		if !other.Contains(cpu) {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return CPUSet{cpus: cpus}
+}
+
+// List returns the CPUs in ascending order
+func (s CPUSet) List() []int {
+	return append([]int(nil), s.cpus...)
+}
+
+// ForEach calls f for every CPU in ascending order
+func (s CPUSet) ForEach(f func(cpu int)) {
+	for _, cpu := range s.cpus {
+		f(cpu)
+	}
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCPUSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "Empty", input: "", want: ""},
+		{name: "Canonical", input: "0-7,64-71", want: "0-7,64-71"},
+		{name: "Duplicates And Overlaps", input: "4,0-3,2-5,5", want: "0-5"},
+		{name: "Pair", input: "3,4", want: "3-4"},
+		{name: "Stride", input: "0-31:8", want: "0,8,16,24"},
+		{name: "Stride Past End", input: "1-6:2", want: "1,3,5"},
+		{name: "Group", input: "0-15:2/8", want: "0-1,8-9"},
+		{name: "Group Clipped At End", input: "0-9:3/8", want: "0-2,8-9"},
+		{name: "Zero Stride", input: "0-31:0", wantErr: true},
+		{name: "Used Above Group", input: "0-31:9/8", wantErr: true},
+		{name: "Invalid Stride", input: "0-31:x", wantErr: true},
+		{name: "Negative", input: "-1", wantErr: true},
+		{name: "Too Large", input: "0-100000000", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCPUSet(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCPUSet() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("ParseCPUSet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCPUSet(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []int
+		want  string
+	}{
+		{name: "Empty", input: nil, want: ""},
+		{name: "Single CPU", input: []int{5}, want: "5"},
+		{name: "Ranges", input: []int{0, 1, 2, 3, 64, 65, 66, 67}, want: "0-3,64-67"},
+		{name: "Mixed", input: []int{0, 1, 2, 4, 6, 7, 8}, want: "0-2,4,6-8"},
+		{name: "Unsorted With Duplicates", input: []int{3, 1, 2, 2, 9}, want: "1-3,9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewCPUSet(tt.input...)
+			if got.String() != tt.want {
+				t.Errorf("NewCPUSet() = %q, want %q", got, tt.want)
+			}
+			// The output parses back to the sorted, unique input
+			parsed, err := ParseCPUSet(got.String())
+			if err != nil || !parsed.Equals(got) {
+				t.Errorf("ParseCPUSet(%q) = %q, %v", got, parsed, err)
+			}
+		})
+	}
+}
+
+func TestCPUSetAlgebra(t *testing.T) {
+	a := NewCPUSet(0, 1, 2, 3, 8, 9)
+	b := NewCPUSet(2, 3, 4, 5, 9)
+
+	tests := []struct {
+		name string
+		got  CPUSet
+		want string
+	}{
+		{name: "Union", got: a.Union(b), want: "0-5,8-9"},
+		{name: "Union Of Several", got: a.Union(b, NewCPUSet(63)), want: "0-5,8-9,63"},
+		{name: "Intersection", got: a.Intersection(b), want: "2-3,9"},
+		{name: "Difference", got: a.Difference(b), want: "0-1,8"},
+		{name: "Difference With Empty", got: a.Difference(CPUSet{}), want: "0-3,8-9"},
+		{name: "Intersection With Empty", got: CPUSet{}.Intersection(a), want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got.String() != tt.want {
+				t.Errorf("%s = %q, want %q", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+
+	if !a.Contains(8) || a.Contains(4) {
+		t.Errorf("Contains() is wrong for %s", a)
+	}
+	if a.Size() != 6 || !(CPUSet{}).IsEmpty() {
+		t.Errorf("Size() = %d, want 6", a.Size())
+	}
+	if !a.Equals(NewCPUSet(9, 8, 3, 2, 1, 0, 0)) || a.Equals(b) {
+		t.Errorf("Equals() is wrong for %s", a)
+	}
+	var visited []int
+	a.ForEach(func(cpu int) { visited = append(visited, cpu) })
+	if !reflect.DeepEqual(visited, a.List()) || !reflect.DeepEqual(visited, []int{0, 1, 2, 3, 8, 9}) {
+		t.Errorf("ForEach() visited %v, List() = %v", visited, a.List())
+	}
+
+	// Sets are immutable
+	list := a.List()
+	list[0] = 42
+	if a.Contains(42) {
+		t.Errorf("List() exposes the set")
+	}
+}
+
+func FuzzParseCPUSet(f *testing.F) {
+	for _, seed := range []string{"", "0", "0-7,64-71", "3,1,2", "0-31:2", "0-63:4/16", " 1 , 5-6 ", "1-2-3", "a"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		set, err := ParseCPUSet(input)
+		if err != nil {
+			return
+		}
+		formatted := set.String()
+		reparsed, err := ParseCPUSet(formatted)
+		if err != nil {
+			t.Fatalf("ParseCPUSet(%q) of the formatted %q failed: %v", formatted, input, err)
+		}
+		if !reparsed.Equals(set) || reparsed.String() != formatted {
+			t.Fatalf("%q formats to %q, which parses to %q", input, formatted, reparsed)
+		}
+		if NewCPUSet(set.List()...).String() != formatted {
+			t.Fatalf("NewCPUSet(%v) = %q, want %q", set.List(), NewCPUSet(set.List()...), formatted)
+		}
+	})
+}
+
+func FuzzCPUSetAlgebra(f *testing.F) {
+	f.Add("0-7", "4-11")
+	f.Add("0-31:2", "1-31:2")
+	f.Add("", "5")
+	f.Fuzz(func(t *testing.T, first, second string) {
+		a, err := ParseCPUSet(first)
+		if err != nil {
+			return
+		}
+		b, err := ParseCPUSet(second)
+		if err != nil {
+			return
+		}
+		union, intersection := a.Union(b), a.Intersection(b)
+		if union.Size() != a.Size()+b.Size()-intersection.Size() {
+			t.Fatalf("|%s ∪ %s| = %d, inconsistent with the intersection %s", a, b, union.Size(), intersection)
+		}
+		if !a.Difference(b).Union(intersection).Equals(a) {
+			t.Fatalf("(%s - %s) ∪ (%s ∩ %s) != %s", a, b, a, b, a)
+		}
+		if !a.Difference(b).Intersection(b).IsEmpty() {
+			t.Fatalf("%s - %s overlaps %s", a, b, b)
+		}
+		intersection.ForEach(func(cpu int) {
+			if !a.Contains(cpu) || !b.Contains(cpu) {
+				t.Fatalf("%s ∩ %s contains %d", a, b, cpu)
+			}
+		})
+	})
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NoNUMANode is the NumaNodeID of CPUs for which lscpu reports no NUMA node, e.g. on
+// kernels without NUMA support
+const NoNUMANode = -1
+
+// LSCPUColumns are the columns ParseLSCPUJSON expects, pass them as lscpu -J -e=<columns>
+const LSCPUColumns = "CPU,CORE,SOCKET,NODE,CACHE,ONLINE,MAXMHZ,BOOK,DRAWER"
+
+// Errors wrapped by LSCPUError
+var (
+	// ErrLSCPUMissingValue is a required column that is absent or empty
+	ErrLSCPUMissingValue = errors.New("missing value")
+	// ErrLSCPUInvalidValue is a column that does not hold a value of its type
+	ErrLSCPUInvalidValue = errors.New("invalid value")
+)
+
+// LSCPUError reports a value in the output of lscpu that cannot be used
+type LSCPUError struct {
+	// Entry is the index of the CPU in the output
+	Entry  int
+	Column string
+	Value  string
+	Err    error
+}
+
+func (e *LSCPUError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("lscpu entry %d: column %s: %v", e.Entry, e.Column, e.Err)
+	}
+	return fmt.Sprintf("lscpu entry %d: column %s: %v %q", e.Entry, e.Column, e.Err, e.Value)
+}
+
+func (e *LSCPUError) Unwrap() error {
+	return e.Err
+}
+
+// lscpuBareDash matches the unquoted "-" that some util-linux versions, e.g. 2.38, emit for
+// missing numeric values, which is not valid JSON
+var lscpuBareDash = regexp.MustCompile(`(:\s*)-(\s*[,}\]])`)
+
+// ParseLSCPUJSON parses the output of lscpu -J -e=<LSCPUColumns>. Values may be JSON numbers
+// and booleans or, from older util-linux versions, strings. CPU, CORE and SOCKET are required
+// for online CPUs. Offline CPUs whose core or socket is unknown get -1, CPUs without a NUMA
+// node get NoNUMANode, and BOOK and DRAWER are 0 unless reported.
+func ParseLSCPUJSON(output []byte) ([]CPUInfo, error) {
+	var document struct {
+		CPUs []map[string]json.RawMessage `json:"cpus"`
+	}
+	if err := json.Unmarshal(lscpuBareDash.ReplaceAll(output, []byte("${1}null${2}")), &document); err != nil {
+		return nil, fmt.Errorf("invalid lscpu JSON: %w", err)
+	}
+
+	cpuInfos := make([]CPUInfo, 0, len(document.CPUs))
+	for entry, columns := range document.CPUs {
+		values := make(map[string]string, len(columns))
+		for key, raw := range columns {
+			value, err := lscpuValue(raw)
+			if err != nil {
+				return nil, &LSCPUError{Entry: entry, Column: key, Value: string(raw), Err: ErrLSCPUInvalidValue}
+			}
+			// The CACHE column is named after the cache levels, e.g. "l1d:l1i:l2:l3"
+			if strings.Contains(key, ":") {
+				key = "cache"
+			}
+			values[key] = value
+		}
+
+		// parseInt parses an integer column, missing values are an error or return fallback
+		parseInt := func(column string, required bool, fallback int) (int, error) {
+			value := values[column]
+			if value == "" {
+				if required {
+					return 0, &LSCPUError{Entry: entry, Column: column, Err: ErrLSCPUMissingValue}
+				}
+				return fallback, nil
+			}
+			number, err := strconv.Atoi(value)
+			if err != nil || number < 0 {
+				return 0, &LSCPUError{Entry: entry, Column: column, Value: value, Err: ErrLSCPUInvalidValue}
+			}
+			return number, nil
+		}
+
+		cpuInfo := CPUInfo{Cache: values["cache"]}
+		switch values["online"] {
+		case "", "yes", "true":
+		case "no", "false":
+			cpuInfo.Offline = true
+		default:
+			return nil, &LSCPUError{Entry: entry, Column: "online", Value: values["online"], Err: ErrLSCPUInvalidValue}
+		}
+		var err error
+		if cpuInfo.CPUID, err = parseInt("cpu", true, 0); err != nil {
+			return nil, err
+		}
+		// The kernel does not report the topology of offline CPUs
+		if cpuInfo.CoreID, err = parseInt("core", !cpuInfo.Offline, -1); err != nil {
+			return nil, err
+		}
+		if cpuInfo.SocketID, err = parseInt("socket", !cpuInfo.Offline, -1); err != nil {
+			return nil, err
+		}
+		if cpuInfo.NumaNodeID, err = parseInt("node", false, NoNUMANode); err != nil {
+			return nil, err
+		}
+		if cpuInfo.Book, err = parseInt("book", false, 0); err != nil {
+			return nil, err
+		}
+		if cpuInfo.Drawer, err = parseInt("drawer", false, 0); err != nil {
+			return nil, err
+		}
+		if value := values["maxmhz"]; value != "" {
+			if cpuInfo.MaxMHz, err = strconv.ParseFloat(value, 64); err != nil {
+				return nil, &LSCPUError{Entry: entry, Column: "maxmhz", Value: value, Err: ErrLSCPUInvalidValue}
+			}
+		}
+		cpuInfos = append(cpuInfos, cpuInfo)
+	}
+	return cpuInfos, nil
+}
+
+// lscpuValue converts a JSON value of lscpu into a string, "" for missing values
+func lscpuValue(raw json.RawMessage) (string, error) {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", err
+	}
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		if v == "-" {
+			return "", nil
+		}
+		return strings.TrimSpace(v), nil
+	case float64:
+		return string(raw), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("unexpected value %s", raw)
+}
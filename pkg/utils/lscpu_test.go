@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseLSCPUJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []CPUInfo
+		wantErr error
+	}{
+		{
+			name: "Typed Values",
+			input: `{"cpus": [
+				{"cpu": 0, "core": 0, "socket": 0, "node": 0, "l1d:l1i:l2:l3": "0:0:0:0", "online": true, "maxmhz": 3500.0000, "book": null, "drawer": null},
+				{"cpu": 1, "core": 0, "socket": 0, "node": 1, "l1d:l1i:l2:l3": "0:0:0:0", "online": true, "maxmhz": 3500.0000, "book": 2, "drawer": 1}
+			]}`,
+			want: []CPUInfo{
+				{CPUID: 0, CoreID: 0, SocketID: 0, NumaNodeID: 0, Cache: "0:0:0:0", MaxMHz: 3500},
+				{CPUID: 1, CoreID: 0, SocketID: 0, NumaNodeID: 1, Cache: "0:0:0:0", MaxMHz: 3500, Book: 2, Drawer: 1},
+			},
+		},
+		{
+			// util-linux 2.38 emits an unquoted "-" for missing numbers
+			name: "Bare Dash",
+			input: `{"cpus": [
+				{"cpu": 0, "core": 0, "socket": 0, "node": 0, "l1d:l1i:l2": "0:0:0", "online": true, "maxmhz": -, "book": -, "drawer": "-"}
+			]}`,
+			want: []CPUInfo{{CPUID: 0, CoreID: 0, SocketID: 0, NumaNodeID: 0, Cache: "0:0:0"}},
+		},
+		{
+			name: "String Values With Offline CPU",
+			input: `{"cpus": [
+				{"cpu":"0", "core":"0", "socket":"0", "node":"0", "online":"yes", "maxmhz":"2400.0000"},
+				{"cpu":"1", "core":"-", "socket":"-", "node":"-", "online":"no", "maxmhz":"-"}
+			]}`,
+			want: []CPUInfo{
+				{CPUID: 0, CoreID: 0, SocketID: 0, NumaNodeID: 0, MaxMHz: 2400},
+				{CPUID: 1, CoreID: -1, SocketID: -1, NumaNodeID: NoNUMANode, Offline: true},
+			},
+		},
+		{
+			name:  "No NUMA",
+			input: `{"cpus": [{"cpu": 0, "core": 0, "socket": 0, "node": null, "online": true}]}`,
+			want:  []CPUInfo{{CPUID: 0, CoreID: 0, SocketID: 0, NumaNodeID: NoNUMANode}},
+		},
+		{
+			name:    "Missing Core Of Online CPU",
+			input:   `{"cpus": [{"cpu": 0, "socket": 0, "node": 0, "online": true}]}`,
+			wantErr: ErrLSCPUMissingValue,
+		},
+		{
+			name:    "Invalid Socket",
+			input:   `{"cpus": [{"cpu": 0, "core": 0, "socket": "x", "node": 0}]}`,
+			wantErr: ErrLSCPUInvalidValue,
+		},
+		{
+			name:    "Invalid Online",
+			input:   `{"cpus": [{"cpu": 0, "core": 0, "socket": 0, "online": "maybe"}]}`,
+			wantErr: ErrLSCPUInvalidValue,
+		},
+		{
+			name:    "Nested Value",
+			input:   `{"cpus": [{"cpu": [0], "core": 0, "socket": 0}]}`,
+			wantErr: ErrLSCPUInvalidValue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLSCPUJSON([]byte(tt.input))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseLSCPUJSON() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) && tt.wantErr == nil {
+				t.Errorf("ParseLSCPUJSON() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLSCPUError(t *testing.T) {
+	_, err := ParseLSCPUJSON([]byte(`{"cpus": [{"cpu": 0, "core": 0, "socket": 0}, {"cpu": 1, "core": "a", "socket": 0}]}`))
+	var lscpuErr *LSCPUError
+	if !errors.As(err, &lscpuErr) {
+		t.Fatalf("ParseLSCPUJSON() error = %v, want an *LSCPUError", err)
+	}
+	if lscpuErr.Entry != 1 || lscpuErr.Column != "core" || lscpuErr.Value != "a" {
+		t.Errorf("LSCPUError = %+v", lscpuErr)
+	}
+	if err.Error() != `lscpu entry 1: column core: invalid value "a"` {
+		t.Errorf("Error() = %q", err.Error())
+	}
+
+	if _, err := ParseLSCPUJSON([]byte(`{"cpus": [`)); err == nil {
+		t.Errorf("ParseLSCPUJSON() accepted truncated JSON")
+	}
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"log"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
@@ -20,10 +21,19 @@ func Atoi(s string) int {
 
 // CPUInfo represents information for a single CPU
 type CPUInfo struct {
-	CPUID      int
-	CoreID     int
-	SocketID   int
+	CPUID    int
+	CoreID   int
+	SocketID int
+	// NumaNodeID is NoNUMANode if lscpu reports no NUMA node for the CPU
 	NumaNodeID int
+	// Cache are the IDs of the caches of the CPU, e.g. "0:0:0:0" for L1d:L1i:L2:L3
+	Cache   string
+	Offline bool
+	// MaxMHz is the maximum frequency of the CPU, 0 if unknown
+	MaxMHz float64
+	// Book and Drawer are the s390 topology levels above the socket, 0 on other architectures
+	Book   int
+	Drawer int
 }
 
 // Logger interface defines logging methods
@@ -77,7 +87,12 @@ func (l *SimpleLogger) formatLog(msg string) string {
 	return fmt.Sprintf("%s:%d %s", file, line, msg)
 }
 
-// ParseCPUList parses a CPU list string and returns a slice of CPU core numbers as integers
+// maxCPU bounds CPU numbers in CPU lists, above the largest NR_CPUS of any kernel
+const maxCPU = 1 << 16
+
+// ParseCPUList parses a CPU list string and returns a slice of CPU core numbers as integers.
+// Besides single numbers and ranges such as "0-3", it accepts a stride, "0-31:2" for every
+// second CPU, and the kernel group syntax, "0-31:2/8" for the first 2 CPUs of every 8.
 func ParseCPUList(cpuListStr string) ([]int, error) {
 	cpuCores := make([]int, 0)
 	if cpuListStr == "" {
@@ -89,39 +104,79 @@ func ParseCPUList(cpuListStr string) ([]int, error) {
 		if segment == "" {
 			continue
 		}
-		if strings.Contains(segment, "-") {
-			// Handle range, e.g., "0-3"
-			bounds := strings.Split(segment, "-")
-			if len(bounds) != 2 {
-				return nil, fmt.Errorf("invalid CPU range: %s", segment)
-			}
-			start, err := strconv.Atoi(bounds[0])
-			if err != nil {
-				return nil, fmt.Errorf("invalid CPU number: %s", bounds[0])
-			}
-			end, err := strconv.Atoi(bounds[1])
-			if err != nil {
-				return nil, fmt.Errorf("invalid CPU number: %s", bounds[1])
-			}
-			if start > end {
-				return nil, fmt.Errorf("invalid CPU range: %s", segment)
-			}
-			for i := start; i <= end; i++ {
-				cpuCores = append(cpuCores, i)
+		cpus, err := parseCPUListSegment(segment)
+		if err != nil {
+			return nil, err
+		}
+		cpuCores = append(cpuCores, cpus...)
+	}
+	return cpuCores, nil
+}
+
+// parseCPUListSegment parses one comma separated segment of a CPU list
+func parseCPUListSegment(segment string) ([]int, error) {
+	if !strings.Contains(segment, "-") {
+		// Handle single number, e.g., "5"
+		cpuNum, err := parseCPUNumber(segment)
+		if err != nil {
+			return nil, err
+		}
+		return []int{cpuNum}, nil
+	}
+
+	// Handle range, e.g., "0-3", with an optional stride or group, e.g., "0-31:2/8"
+	rangePart, stridePart, hasStride := strings.Cut(segment, ":")
+	bounds := strings.Split(rangePart, "-")
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("invalid CPU range: %s", segment)
+	}
+	start, err := parseCPUNumber(bounds[0])
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseCPUNumber(bounds[1])
+	if err != nil {
+		return nil, err
+	}
+	if start > end {
+		return nil, fmt.Errorf("invalid CPU range: %s", segment)
+	}
+	used, group := 1, 1
+	if hasStride {
+		usedPart, groupPart, hasGroup := strings.Cut(stridePart, "/")
+		if hasGroup {
+			used, err = strconv.Atoi(usedPart)
+			if err == nil {
+				group, err = strconv.Atoi(groupPart)
 			}
 		} else {
-			// Handle single number, e.g., "5"
-			cpuNum, err := strconv.Atoi(segment)
-			if err != nil {
-				return nil, fmt.Errorf("invalid CPU number: %s", segment)
-			}
-			cpuCores = append(cpuCores, cpuNum)
+			group, err = strconv.Atoi(stridePart)
+		}
+		if err != nil || used < 1 || group < 1 || used > group {
+			return nil, fmt.Errorf("invalid CPU stride: %s", segment)
 		}
 	}
-	return cpuCores, nil
+	cpus := make([]int, 0, (end-start)/group*used+used)
+	for i := start; i <= end; i += group {
+		for j := i; j < i+used && j <= end; j++ {
+			cpus = append(cpus, j)
+		}
+	}
+	return cpus, nil
+}
+
+// parseCPUNumber parses a single CPU number
+func parseCPUNumber(s string) (int, error) {
+	cpuNum, err := strconv.Atoi(s)
+	if err != nil || cpuNum < 0 || cpuNum >= maxCPU {
+		return 0, fmt.Errorf("invalid CPU number: %s", s)
+	}
+	return cpuNum, nil
 }
 
-// ParseLSCPUOutput parses the output of the lscpu command
+// ParseLSCPUOutput parses the output of lscpu -p=CPU,Core,Socket,Node, which only lists
+// online CPUs. An empty Node column becomes NoNUMANode. Prefer ParseLSCPUJSON, which
+// reports malformed values.
 func ParseLSCPUOutput(output string) []CPUInfo {
 	lines := strings.Split(output, "\n")
 	var cpuInfos []CPUInfo
@@ -136,7 +191,10 @@ func ParseLSCPUOutput(output string) []CPUInfo {
 		cpuID := Atoi(fields[0])
 		coreID := Atoi(fields[1])
 		socketID := Atoi(fields[2])
-		numaNodeID := Atoi(fields[3])
+		numaNodeID := NoNUMANode
+		if fields[3] != "" {
+			numaNodeID = Atoi(fields[3])
+		}
 
 		cpuInfo := CPUInfo{
 			CPUID:      cpuID,
@@ -148,3 +206,195 @@ func ParseLSCPUOutput(output string) []CPUInfo {
 	}
 	return cpuInfos
 }
+
+// ParseNUMAMaps aggregates the content of /proc/<pid>/numa_maps into resident bytes per NUMA node
+func ParseNUMAMaps(content string) map[int]int64 {
+	numaMemory := make(map[int]int64)
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		pageSize := int64(4096) // default page size
+		pages := make(map[int]int64)
+		for _, field := range fields {
+			key, value, found := strings.Cut(field, "=")
+			if !found {
+				continue
+			}
+			if key == "kernelpagesize_kB" {
+				if kb, err := strconv.ParseInt(value, 10, 64); err == nil {
+					pageSize = kb * 1024
+				}
+				continue
+			}
+			if !strings.HasPrefix(key, "N") {
+				continue
+			}
+			numaNodeID, err := strconv.Atoi(key[1:])
+			if err != nil {
+				continue
+			}
+			count, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			pages[numaNodeID] += count
+		}
+		for numaNodeID, count := range pages {
+			numaMemory[numaNodeID] += count * pageSize
+		}
+	}
+	return numaMemory
+}
+
+// ParseMemoryNUMAStat parses a cgroup v2 memory.numa_stat file and returns the anonymous
+// and file-backed bytes charged to the cgroup per NUMA node
+func ParseMemoryNUMAStat(content string) (map[int]int64, error) {
+	numaMemory := make(map[int]int64)
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || (fields[0] != "anon" && fields[0] != "file") {
+			continue
+		}
+		for _, field := range fields[1:] {
+			key, value, found := strings.Cut(field, "=")
+			if !found || !strings.HasPrefix(key, "N") {
+				return nil, fmt.Errorf("invalid memory.numa_stat entry: %s", field)
+			}
+			numaNodeID, err := strconv.Atoi(key[1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid NUMA node in memory.numa_stat: %s", key)
+			}
+			bytes, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid byte count in memory.numa_stat: %s", value)
+			}
+			numaMemory[numaNodeID] += bytes
+		}
+	}
+	return numaMemory, nil
+}
+
+// CPUTimes holds the cumulative busy and total jiffies of a CPU from /proc/stat
+type CPUTimes struct {
+	Busy  uint64
+	Total uint64
+}
+
+// ParseProcStat parses the per-CPU lines of /proc/stat, keyed by CPU number
+func ParseProcStat(content string) (map[int]CPUTimes, error) {
+	cpuTimes := make(map[int]CPUTimes)
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		// Skip the aggregate "cpu" line and non-CPU lines
+		if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") || fields[0] == "cpu" {
+			continue
+		}
+		cpuID, err := strconv.Atoi(strings.TrimPrefix(fields[0], "cpu"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU in /proc/stat: %s", fields[0])
+		}
+		var times CPUTimes
+		// Fields: user nice system idle iowait irq softirq steal guest guest_nice.
+		// guest time is already accounted in user, so only the first 8 columns count.
+		for i, field := range fields[1:] {
+			if i >= 8 {
+				break
+			}
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid time for %s in /proc/stat: %s", fields[0], field)
+			}
+			times.Total += value
+			// idle and iowait
+			if i != 3 && i != 4 {
+				times.Busy += value
+			}
+		}
+		cpuTimes[cpuID] = times
+	}
+	return cpuTimes, nil
+}
+
+// CPUUtilization returns the busy percentage of each CPU between two /proc/stat samples
+func CPUUtilization(prev, cur map[int]CPUTimes) map[int]float64 {
+	utilization := make(map[int]float64)
+	for cpuID, curTimes := range cur {
+		prevTimes, ok := prev[cpuID]
+		if !ok || curTimes.Total <= prevTimes.Total || curTimes.Busy < prevTimes.Busy {
+			continue
+		}
+		busy := float64(curTimes.Busy - prevTimes.Busy)
+		total := float64(curTimes.Total - prevTimes.Total)
+		utilization[cpuID] = busy / total * 100
+	}
+	return utilization
+}
+
+// ParseCgroupCPUUsage returns usage_usec from a cgroup v2 cpu.stat file
+func ParseCgroupCPUUsage(content string) (uint64, error) {
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "usage_usec" {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("usage_usec not found in cpu.stat")
+}
+
+// NormalizePCIAddress converts a PCI address as printed by nvidia-smi ("00000000:1B:00.0")
+// to the sysfs form ("0000:1b:00.0")
+func NormalizePCIAddress(address string) string {
+	address = strings.ToLower(strings.TrimSpace(address))
+	if domain, rest, found := strings.Cut(address, ":"); found && len(domain) > 4 {
+		address = domain[len(domain)-4:] + ":" + rest
+	}
+	return address
+}
+
+// ParseNodeMeminfo parses /sys/devices/system/node/node<N>/meminfo and returns
+// MemTotal and MemFree in KiB
+func ParseNodeMeminfo(content string) (int, int, error) {
+	memTotal, memFree := -1, -1
+	for _, line := range strings.Split(content, "\n") {
+		// Lines look like "Node 0 MemTotal:       131596288 kB"
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		value, err := strconv.Atoi(fields[3])
+		if err != nil {
+			continue
+		}
+		switch fields[2] {
+		case "MemTotal:":
+			memTotal = value
+		case "MemFree:":
+			memFree = value
+		}
+	}
+	if memTotal < 0 || memFree < 0 {
+		return 0, 0, fmt.Errorf("MemTotal or MemFree not found in node meminfo")
+	}
+	return memTotal, memFree, nil
+}
+
+// PCIeSwitch returns the address of the upstream port of the PCIe switch a device sits
+// behind, given the resolved sysfs path of the device, e.g.
+// /sys/devices/pci0000:00/0000:00:01.0/0000:01:00.0/0000:02:08.0/0000:03:00.0.
+// It returns "" for devices attached directly to a root port.
+func PCIeSwitch(devicePath string) string {
+	var bridges []string
+	for _, element := range strings.Split(devicePath, "/") {
+		if pciAddressPattern.MatchString(element) {
+			bridges = append(bridges, element)
+		}
+	}
+	// The last element is the device itself, a switch adds an upstream and a downstream
+	// port between it and the root port
+	if len(bridges) < 4 {
+		return ""
+	}
+	return bridges[len(bridges)-3]
+}
+
+// pciAddressPattern matches a PCI address in domain:bus:device.function form
+var pciAddressPattern = regexp.MustCompile(`^[0-9a-f]{4}:[0-9a-f]{2}:[0-9a-f]{2}\.[0-7]$`)
@@ -87,3 +87,99 @@ func TestParseCPUList(t *testing.T) {
 		})
 	}
 }
+
+func TestParseNUMAMaps(t *testing.T) {
+	content := `55d0c0a00000 default file=/usr/bin/python3.10 mapped=4 mapmax=2 N0=4 kernelpagesize_kB=4
+7f3a40000000 default anon=262144 dirty=262144 N0=1000 N1=261144 kernelpagesize_kB=4
+7f3b00000000 default file=/anon_hugepage\040(deleted) huge anon=2 dirty=2 N1=2 kernelpagesize_kB=2048
+7ffd5e5f0000 default stack anon=3 dirty=3 active=0 N0=3 kernelpagesize_kB=4
+`
+	got := ParseNUMAMaps(content)
+	want := map[int]int64{
+		0: (4 + 1000 + 3) * 4096,
+		1: 261144*4096 + 2*2048*1024,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseNUMAMaps() = %v, want %v", got, want)
+	}
+}
+
+func TestParseMemoryNUMAStat(t *testing.T) {
+	content := `anon N0=1048576 N1=4194304
+file N0=2097152 N1=0
+kernel_stack N0=32768 N1=16384
+shmem N0=0 N1=0
+`
+	got, err := ParseMemoryNUMAStat(content)
+	if err != nil {
+		t.Fatalf("ParseMemoryNUMAStat() error = %v", err)
+	}
+	want := map[int]int64{0: 3145728, 1: 4194304}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseMemoryNUMAStat() = %v, want %v", got, want)
+	}
+
+	if _, err := ParseMemoryNUMAStat("anon total=5"); err == nil {
+		t.Errorf("ParseMemoryNUMAStat() expected an error for an invalid entry")
+	}
+}
+
+func TestCPUUtilization(t *testing.T) {
+	prev, err := ParseProcStat(`cpu  300 0 300 1200 200 0 0 0 0 0
+cpu0 100 0 100 600 100 0 0 0 0 0
+cpu1 200 0 200 600 100 0 0 0 0 0
+intr 12345 0 0
+`)
+	if err != nil {
+		t.Fatalf("ParseProcStat() error = %v", err)
+	}
+	cur, err := ParseProcStat(`cpu  650 0 300 1350 200 0 0 0 0 0
+cpu0 150 0 100 750 100 0 0 0 0 0
+cpu1 500 0 200 600 100 0 0 0 0 0
+cpu2 10 0 10 10 0 0 0 0 0 0
+`)
+	if err != nil {
+		t.Fatalf("ParseProcStat() error = %v", err)
+	}
+
+	got := CPUUtilization(prev, cur)
+	// cpu0: 50 busy of 200, cpu1: 300 busy of 300, cpu2 has no previous sample
+	want := map[int]float64{0: 25, 1: 100}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CPUUtilization() = %v, want %v", got, want)
+	}
+
+	if _, err := ParseProcStat("cpu0 1 2 x 4 5"); err == nil {
+		t.Errorf("ParseProcStat() expected an error for an invalid time")
+	}
+}
+
+func TestParseCgroupCPUUsage(t *testing.T) {
+	got, err := ParseCgroupCPUUsage("usage_usec 123456\nuser_usec 100000\nsystem_usec 23456\n")
+	if err != nil || got != 123456 {
+		t.Errorf("ParseCgroupCPUUsage() = %d, %v, want 123456", got, err)
+	}
+	if _, err := ParseCgroupCPUUsage("nr_periods 0\n"); err == nil {
+		t.Errorf("ParseCgroupCPUUsage() expected an error without usage_usec")
+	}
+}
+
+func TestPCIeSwitch(t *testing.T) {
+	tests := []struct {
+		name       string
+		devicePath string
+		want       string
+	}{
+		{"behind a switch", "/sys/devices/pci0000:00/0000:00:01.0/0000:01:00.0/0000:02:08.0/0000:03:00.0", "0000:01:00.0"},
+		{"behind two switches", "/sys/devices/pci0000:16/0000:16:02.0/0000:17:00.0/0000:18:10.0/0000:19:00.0/0000:1a:00.0/0000:1b:00.0", "0000:19:00.0"},
+		{"on a root port", "/sys/devices/pci0000:00/0000:00:03.0/0000:04:00.0", ""},
+		{"not a PCI path", "/sys/devices/virtual/net/lo", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PCIeSwitch(tt.devicePath); got != tt.want {
+				t.Errorf("PCIeSwitch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
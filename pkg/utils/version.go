@@ -0,0 +1,5 @@
+package utils
+
+// Version is the version of the agent, set at build time with
+// -ldflags "-X flextopo/pkg/utils.Version=<version>"
+var Version = "dev"